@@ -2,11 +2,17 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	"github.com/erknas/song-library/internal/api"
 	"github.com/erknas/song-library/internal/config"
 	"github.com/erknas/song-library/internal/logger"
+	"github.com/erknas/song-library/internal/metadata"
 	"github.com/erknas/song-library/internal/service"
 	"github.com/erknas/song-library/internal/storage"
 	"github.com/erknas/song-library/migrations"
@@ -14,23 +20,83 @@ import (
 
 func main() {
 	var (
-		ctx    = context.Background()
 		cfg    = config.Load()
 		logger = logger.New(cfg.Env)
 	)
 
-	if err := migrations.New(cfg); err != nil {
-		log.Fatalf("migrations failed: %s", err)
-	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	store, err := storage.NewPostgresPool(ctx, cfg)
+	store, err := newStore(ctx, cfg)
 	if err != nil {
-		log.Fatalf("connect to postgres failed: %s", err)
+		log.Fatalf("failed to init storage: %s", err)
 	}
 	defer store.Close()
 
-	srv := service.New(cfg.ThirdPartyAPIURL, logger, store)
+	cache, err := newDetailsCache(cfg, store)
+	if err != nil {
+		log.Fatalf("failed to init details cache: %s", err)
+	}
+
+	details, err := metadata.New(cfg.DetailsConfig, cache)
+	if err != nil {
+		log.Fatalf("failed to init details provider: %s", err)
+	}
+
+	srv := service.New(details, cache, logger, store)
+
+	// The workers must be done with the store before it is closed.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		srv.RunTrashPurge(ctx, cfg.TrashPurgeInterval, cfg.TrashRetention)
+	}()
+	go func() {
+		defer wg.Done()
+		srv.RunEnrichment(ctx, cfg.EnrichmentConfig)
+	}()
 
 	server := api.NewServer(logger, srv)
 	server.Start(ctx, cfg)
 }
+
+func newStore(ctx context.Context, cfg *config.Config) (storage.Storer, error) {
+	switch cfg.Storage {
+	case config.StorageMemory:
+		return storage.NewMemoryStore(), nil
+	case config.StoragePostgres:
+		if err := migrations.New(cfg); err != nil {
+			return nil, fmt.Errorf("migrations failed: %w", err)
+		}
+
+		store, err := storage.NewPostgresPool(ctx, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("connect to postgres failed: %w", err)
+		}
+
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown storage %q", cfg.Storage)
+	}
+}
+
+// newDetailsCache returns nil if caching is off.
+func newDetailsCache(cfg *config.Config, store storage.Storer) (metadata.DetailsCache, error) {
+	switch cfg.DetailsCache {
+	case config.DetailsCacheNone:
+		return nil, nil
+	case config.DetailsCacheMemory:
+		return metadata.NewLRUCache(cfg.DetailsCacheSize), nil
+	case config.DetailsCachePostgres:
+		pool, ok := store.(*storage.PostgresPool)
+		if !ok {
+			return nil, fmt.Errorf("details cache %q needs storage %q", cfg.DetailsCache, config.StoragePostgres)
+		}
+		return pool.DetailsCache(cfg.DetailsCacheSize), nil
+	default:
+		return nil, fmt.Errorf("unknown details cache %q", cfg.DetailsCache)
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/lib"
+	"github.com/erknas/song-library/internal/types"
+)
+
+//	@Summary		Get a list of albums
+//	@Description	Get a paginated list of albums ordered by release date
+//	@Tags			albums
+//	@Produce		json
+//	@Param			page	query		int	false	"Page number"				default(1)	example(1)
+//	@Param			size	query		int	false	"Number of albums per page"	default(10)	example(10)	Enums(10,25,50)
+//	@Success		200		{object}	types.Albums
+//	@Failure		400		{object}	errs.APIError
+//	@Failure		500		{string}	internal	server	error
+//	@Router			/albums [get]
+func (s *Server) handleGetAlbums(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	pag, err := lib.SongsPaginationValues(r)
+	if err != nil {
+		return err
+	}
+
+	albums, err := s.srv.GetAlbums(ctx, pag)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, types.Albums{Albums: albums})
+}
+
+//	@Summary		Get album
+//	@Description	Get album by ID
+//	@Tags			albums
+//	@Produce		json
+//	@Param			id	path		int	true	"Album ID"
+//	@Success		200	{object}	types.Album
+//	@Failure		400	{object}	errs.APIError
+//	@Failure		404	{object}	errs.APIError
+//	@Failure		500	{string}	internal	server	error
+//	@Router			/albums/{id} [get]
+func (s *Server) handleGetAlbum(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidAlbumID()
+	}
+
+	album, err := s.srv.GetAlbum(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, album)
+}
+
+//	@Summary		Add album
+//	@Description	Add album. The group is created if it doesn't exist yet
+//	@Tags			albums
+//	@Accept			json
+//	@Produce		json
+//	@Param			album	body		types.AlbumRequest	true	"Album data"
+//	@Success		201		{object}	types.Album
+//	@Failure		400		{object}	errs.APIError
+//	@Failure		500		{string}	internal	server	error
+//	@Router			/albums [post]
+func (s *Server) handleAddAlbum(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	req := new(types.AlbumRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return errs.InvalidJSON()
+	}
+	defer r.Body.Close()
+
+	album, err := s.srv.AddAlbum(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusCreated, album)
+}
+
+//	@Summary		Update album
+//	@Description	Update album by ID
+//	@Tags			albums
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Album ID"
+//	@Param			album	body		types.AlbumRequest	true	"Album data"
+//	@Success		200		{object}	types.SongResponse
+//	@Failure		400		{object}	errs.APIError
+//	@Failure		404		{object}	errs.APIError
+//	@Failure		500		{string}	internal	server	error
+//	@Router			/albums/{id} [put]
+func (s *Server) handleUpdateAlbum(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidAlbumID()
+	}
+
+	req := new(types.AlbumRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return errs.InvalidJSON()
+	}
+	defer r.Body.Close()
+
+	if err := s.srv.UpdateAlbum(ctx, id, req); err != nil {
+		return err
+	}
+
+	resp := types.NewSongResponse(http.StatusOK, "album successfully updated")
+
+	return lib.WriteJSON(w, http.StatusOK, resp)
+}
+
+//	@Summary		Delete album
+//	@Description	Delete album by ID. Albums that still have songs can't be deleted
+//	@Tags			albums
+//	@Produce		json
+//	@Param			id	path		int	true	"Album ID"
+//	@Success		200	{object}	types.SongResponse
+//	@Failure		400	{object}	errs.APIError
+//	@Failure		404	{object}	errs.APIError
+//	@Failure		409	{object}	errs.APIError
+//	@Failure		500	{string}	internal	server	error
+//	@Router			/albums/{id} [delete]
+func (s *Server) handleDeleteAlbum(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidAlbumID()
+	}
+
+	if err := s.srv.DeleteAlbum(ctx, id); err != nil {
+		return err
+	}
+
+	resp := types.NewSongResponse(http.StatusOK, "album successfully deleted")
+
+	return lib.WriteJSON(w, http.StatusOK, resp)
+}
+
+//	@Summary		Get album tracks
+//	@Description	Get the album's songs ordered by track number
+//	@Tags			albums
+//	@Produce		json
+//	@Param			id	path		int	true	"Album ID"
+//	@Success		200	{object}	types.Tracks
+//	@Failure		400	{object}	errs.APIError
+//	@Failure		404	{object}	errs.APIError
+//	@Failure		500	{string}	internal	server	error
+//	@Router			/albums/{id}/songs [get]
+func (s *Server) handleGetAlbumTracks(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidAlbumID()
+	}
+
+	tracks, err := s.srv.GetAlbumTracks(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, types.Tracks{Tracks: tracks})
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/erknas/song-library/internal/lib"
+)
+
+//	@Summary		Invalidate song details cache
+//	@Description	Drop the cached details of a song so that the next lookup asks the details API again, whether details were found or not. Without song and group the whole cache is cleared
+//	@Tags			admin
+//	@Produce		json
+//	@Param			song	query		string	false	"Song name"		example(Supermassive Black Hole)
+//	@Param			group	query		string	false	"Group name"	example(Muse)
+//	@Success		200		{object}	types.CacheInvalidation
+//	@Failure		400		{object}	errs.APIError
+//	@Failure		404		{object}	errs.APIError
+//	@Failure		500		{string}	internal	server	error
+//	@Router			/admin/details-cache [delete]
+func (s *Server) handleInvalidateDetailsCache(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	inv, err := s.srv.InvalidateDetailsCache(ctx, r.FormValue("song"), r.FormValue("group"))
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, inv)
+}
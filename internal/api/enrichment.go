@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/lib"
+	"github.com/erknas/song-library/internal/types"
+)
+
+//	@Summary		Get a list of enrichment jobs
+//	@Description	Get a paginated list of the songs whose details haven't been fetched yet, ordered by song ID
+//	@Tags			enrichment
+//	@Produce		json
+//	@Param			status	query		string	false	"Only jobs with this status"	Enums(pending,failed)
+//	@Param			page	query		int		false	"Page number"				default(1)	example(1)
+//	@Param			size	query		int		false	"Number of jobs per page"	default(10)	example(10)	Enums(10,25,50)
+//	@Success		200		{object}	types.Enrichments
+//	@Failure		400		{object}	errs.APIError
+//	@Failure		500		{string}	internal	server	error
+//	@Router			/songs/enrichment [get]
+func (s *Server) handleGetEnrichmentJobs(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	pag, err := lib.SongsPaginationValues(r)
+	if err != nil {
+		return err
+	}
+
+	jobs, err := s.srv.GetEnrichmentJobs(ctx, r.FormValue("status"), pag)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, types.Enrichments{Jobs: jobs})
+}
+
+//	@Summary		Get song enrichment
+//	@Description	Get the status of fetching the song's details: pending, done or failed, with the number of attempts and the last error
+//	@Tags			enrichment
+//	@Produce		json
+//	@Param			id	path		int	true	"Song ID"
+//	@Success		200	{object}	types.Enrichment
+//	@Failure		400	{object}	errs.APIError
+//	@Failure		404	{object}	errs.APIError
+//	@Failure		500	{string}	internal	server	error
+//	@Router			/songs/{id}/enrichment [get]
+func (s *Server) handleGetSongEnrichment(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidID()
+	}
+
+	job, err := s.srv.GetSongEnrichment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, job)
+}
+
+//	@Summary		Retry song enrichment
+//	@Description	Queue a pending or failed song to have its details fetched again with a fresh set of attempts. Details the song already has are kept
+//	@Tags			enrichment
+//	@Produce		json
+//	@Param			id	path		int	true	"Song ID"
+//	@Success		202	{object}	types.Enrichment
+//	@Failure		400	{object}	errs.APIError
+//	@Failure		404	{object}	errs.APIError
+//	@Failure		409	{object}	errs.APIError
+//	@Failure		500	{string}	internal	server	error
+//	@Router			/songs/{id}/enrichment/retry [post]
+func (s *Server) handleRetrySongEnrichment(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidID()
+	}
+
+	job, err := s.srv.RetrySongEnrichment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusAccepted, job)
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/erknas/song-library/internal/lib"
+)
+
+// exportTimeout bounds the streaming of a whole library export.
+const exportTimeout = time.Minute * 30
+
+//	@Summary		Export songs
+//	@Description	Stream every song matching the filters in ID order as a JSON array, CSV with a header row or JSON Lines. The CSV columns can be imported again with POST /songs/import
+//	@Tags			songs
+//	@Produce		json
+//	@Produce		text/csv
+//	@Produce		application/x-ndjson
+//	@Param			format	query		string	false	"Export format"	default(json)	Enums(json,csv,ndjson)
+//	@Param			song	query		string	false	"Filter by song"			example(Supermassive Black Hole)
+//	@Param			group	query		string	false	"Filter by group"			example(Muse)
+//	@Param			date	query		string	false	"Filter by release_date"	example(16.07.2006)
+//	@Param			dateFrom	query	string	false	"Release date from, inclusive"	example(01.01.2000)
+//	@Param			dateTo	query		string	false	"Release date to, inclusive"	example(31.12.2009)
+//	@Param			year	query		int		false	"Filter by release year"	example(2006)
+//	@Param			decade	query		string	false	"Filter by release decade"	example(2000s)
+//	@Param			match	query		string	false	"Match mode for song and group"	default(exact)	Enums(exact,prefix,fuzzy)
+//	@Success		200		{array}		types.Song
+//	@Failure		400		{object}	errs.APIError
+//	@Failure		500		{string}	internal	server	error
+//	@Router			/songs/export [get]
+func (s *Server) handleExportSongs(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	fil, err := lib.FilterValues(r)
+	if err != nil {
+		return err
+	}
+
+	sw, err := lib.NewSongWriter(w, r.FormValue("format"))
+	if err != nil {
+		return err
+	}
+
+	// The server write timeout is sized for regular responses.
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(exportTimeout))
+
+	if err := s.srv.ExportSongs(ctx, fil, sw.Write); err != nil {
+		// Once the status line is out an error can't be reported, so the
+		// connection is dropped to keep the client from taking a
+		// truncated export for a complete one.
+		if sw.Started() {
+			panic(http.ErrAbortHandler)
+		}
+		return err
+	}
+
+	return sw.Close()
+}
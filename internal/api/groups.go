@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/lib"
+	"github.com/erknas/song-library/internal/types"
+)
+
+//	@Summary		Get a list of groups
+//	@Description	Get a paginated list of groups ordered by name
+//	@Tags			groups
+//	@Produce		json
+//	@Param			page	query		int	false	"Page number"				default(1)	example(1)
+//	@Param			size	query		int	false	"Number of groups per page"	default(10)	example(10)	Enums(10,25,50)
+//	@Success		200		{object}	types.Groups
+//	@Failure		400		{object}	errs.APIError
+//	@Failure		500		{string}	internal	server	error
+//	@Router			/groups [get]
+func (s *Server) handleGetGroups(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	pag, err := lib.SongsPaginationValues(r)
+	if err != nil {
+		return err
+	}
+
+	groups, err := s.srv.GetGroups(ctx, pag)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, types.Groups{Groups: groups})
+}
+
+//	@Summary		Get group
+//	@Description	Get group by ID
+//	@Tags			groups
+//	@Produce		json
+//	@Param			id	path		int	true	"Group ID"
+//	@Success		200	{object}	types.Group
+//	@Failure		400	{object}	errs.APIError
+//	@Failure		404	{object}	errs.APIError
+//	@Failure		500	{string}	internal	server	error
+//	@Router			/groups/{id} [get]
+func (s *Server) handleGetGroup(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidGroupID()
+	}
+
+	group, err := s.srv.GetGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, group)
+}
+
+//	@Summary		Add group
+//	@Description	Add group. Names are trimmed and compared case-insensitively
+//	@Tags			groups
+//	@Accept			json
+//	@Produce		json
+//	@Param			group	body		types.GroupRequest	true	"Group data"
+//	@Success		201		{object}	types.Group
+//	@Failure		400		{object}	errs.APIError
+//	@Failure		409		{object}	errs.APIError
+//	@Failure		500		{string}	internal	server	error
+//	@Router			/groups [post]
+func (s *Server) handleAddGroup(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	req := new(types.GroupRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return errs.InvalidJSON()
+	}
+	defer r.Body.Close()
+
+	group, err := s.srv.AddGroup(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusCreated, group)
+}
+
+//	@Summary		Update group
+//	@Description	Rename group by ID
+//	@Tags			groups
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Group ID"
+//	@Param			group	body		types.GroupRequest	true	"Group data"
+//	@Success		200		{object}	types.SongResponse
+//	@Failure		400		{object}	errs.APIError
+//	@Failure		404		{object}	errs.APIError
+//	@Failure		409		{object}	errs.APIError
+//	@Failure		500		{string}	internal	server	error
+//	@Router			/groups/{id} [put]
+func (s *Server) handleUpdateGroup(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidGroupID()
+	}
+
+	req := new(types.GroupRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return errs.InvalidJSON()
+	}
+	defer r.Body.Close()
+
+	if err := s.srv.UpdateGroup(ctx, id, req); err != nil {
+		return err
+	}
+
+	resp := types.NewSongResponse(http.StatusOK, "group successfully updated")
+
+	return lib.WriteJSON(w, http.StatusOK, resp)
+}
+
+//	@Summary		Delete group
+//	@Description	Delete group by ID. Groups that still have songs or albums can't be deleted
+//	@Tags			groups
+//	@Produce		json
+//	@Param			id	path		int	true	"Group ID"
+//	@Success		200	{object}	types.SongResponse
+//	@Failure		400	{object}	errs.APIError
+//	@Failure		404	{object}	errs.APIError
+//	@Failure		409	{object}	errs.APIError
+//	@Failure		500	{string}	internal	server	error
+//	@Router			/groups/{id} [delete]
+func (s *Server) handleDeleteGroup(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidGroupID()
+	}
+
+	if err := s.srv.DeleteGroup(ctx, id); err != nil {
+		return err
+	}
+
+	resp := types.NewSongResponse(http.StatusOK, "group successfully deleted")
+
+	return lib.WriteJSON(w, http.StatusOK, resp)
+}
+
+//	@Summary		Get a list of songs by group
+//	@Description	Get a paginated list of the group's songs
+//	@Tags			groups
+//	@Produce		json
+//	@Param			id		path		int		true	"Group ID"
+//	@Param			page	query		int		false	"Page number"				default(1)	example(1)
+//	@Param			size	query		int		false	"Number of songs per page"	default(10)	example(10)	Enums(10,25,50)
+//	@Param			cursor	query		string	false	"Cursor from nextCursor of the previous page; page is ignored when set"
+//	@Param			sort	query		string	false	"Comma-separated sort fields: id, song, group, releaseDate; prefix with - for descending"	example(-releaseDate)
+//	@Param			If-None-Match	header	string	false	"ETag of a previous response"
+//	@Success		200		{object}	types.Songs
+//	@Success		304		"Not modified"
+//	@Failure		400		{object}	errs.APIError
+//	@Failure		404		{object}	errs.APIError
+//	@Failure		500		{string}	internal	server	error
+//	@Router			/groups/{id}/songs [get]
+func (s *Server) handleGetGroupSongs(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidGroupID()
+	}
+
+	pag, err := lib.SongsPaginationValues(r)
+	if err != nil {
+		return err
+	}
+
+	sort, err := lib.SortValues(r)
+	if err != nil {
+		return err
+	}
+
+	pag.Cursor, err = lib.CursorValue(r)
+	if err != nil {
+		return err
+	}
+
+	q := types.SongsQuery{
+		Filter:     types.Filter{Match: types.MatchExact},
+		Sort:       sort,
+		Pagination: pag,
+	}
+
+	page, err := s.srv.GetGroupSongs(ctx, id, q)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSONETag(w, r, http.StatusOK, songsResponse(r, pag, page))
+}
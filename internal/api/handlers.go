@@ -4,46 +4,32 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/erknas/song-library/internal/errs"
 	"github.com/erknas/song-library/internal/lib"
 	"github.com/erknas/song-library/internal/types"
 )
 
-func (s *Server) handleSong(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	switch r.Method {
-	case http.MethodGet:
-		return s.handleGetSongText(ctx, w, r)
-	case http.MethodDelete:
-		return s.handleDeleteSong(ctx, w, r)
-	case http.MethodPut:
-		return s.handleUpdateSong(ctx, w, r)
-	default:
-		return lib.WriteJSON(w, http.StatusMethodNotAllowed, nil)
-	}
-}
-
-func (s *Server) handleSongs(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	switch r.Method {
-	case http.MethodGet:
-		return s.handleGetSongs(ctx, w, r)
-	case http.MethodPost:
-		return s.handleAddSong(ctx, w, r)
-	default:
-		return lib.WriteJSON(w, http.StatusMethodNotAllowed, nil)
-	}
-}
-
 //	@Summary		Get a list of songs
-//	@Description	Get a paginated list of songs with optional filtering
+//	@Description	Get a paginated list of songs with optional filtering. The response carries the total number of matching songs and links to the neighbouring pages
 //	@Tags			songs
 //	@Produce		json
 //	@Param			page	query		int		false	"Page number"				default(1)	example(1)
 //	@Param			size	query		int		false	"Number of songs per page"	default(10)	example(10)	Enums(10,25,50)
+//	@Param			cursor	query		string	false	"Cursor from nextCursor of the previous page; page is ignored when set"
 //	@Param			song	query		string	false	"Filter by song"			example(Supermassive Black Hole)
 //	@Param			group	query		string	false	"Filter by group"			example(Muse)
 //	@Param			date	query		string	false	"Filter by release_date"	example(16.07.2006)
-//	@Success		200		{object}	[]types.Song
+//	@Param			dateFrom	query	string	false	"Release date from, inclusive"	example(01.01.2000)
+//	@Param			dateTo	query		string	false	"Release date to, inclusive"	example(31.12.2009)
+//	@Param			year	query		int		false	"Filter by release year"	example(2006)
+//	@Param			decade	query		string	false	"Filter by release decade"	example(2000s)
+//	@Param			match	query		string	false	"Match mode for song and group"	default(exact)	Enums(exact,prefix,fuzzy)
+//	@Param			sort	query		string	false	"Comma-separated sort fields: id, song, group, releaseDate; prefix with - for descending"	example(-releaseDate,song)
+//	@Param			If-None-Match	header	string	false	"ETag of a previous response"
+//	@Success		200		{object}	types.Songs
+//	@Success		304		"Not modified"
 //	@Failure		400		{object}	errs.APIError
 //	@Failure		500		{string}	internal	server	error
 //	@Router			/songs [get]
@@ -58,27 +44,128 @@ func (s *Server) handleGetSongs(ctx context.Context, w http.ResponseWriter, r *h
 		return err
 	}
 
-	songs, err := s.srv.GetSongs(ctx, pag, fil)
+	sort, err := lib.SortValues(r)
+	if err != nil {
+		return err
+	}
+
+	pag.Cursor, err = lib.CursorValue(r)
+	if err != nil {
+		return err
+	}
+
+	q := types.SongsQuery{
+		Filter:     fil,
+		Sort:       sort,
+		Pagination: pag,
+	}
+
+	page, err := s.srv.GetSongs(ctx, q)
 	if err != nil {
 		return err
 	}
 
-	return lib.WriteJSON(w, http.StatusOK, types.Songs{Songs: songs})
+	return lib.WriteJSONETag(w, r, http.StatusOK, songsResponse(r, pag, page))
+}
+
+// songsResponse wraps a page of songs with pagination metadata and links to
+// the neighbouring pages of the same listing.
+func songsResponse(r *http.Request, pag types.Pagination, page *types.SongsPage) types.Songs {
+	resp := types.Songs{
+		Songs:      page.Songs,
+		Total:      page.Total,
+		Size:       pag.Size,
+		TotalPages: (page.Total + pag.Size - 1) / pag.Size,
+		NextCursor: lib.EncodeCursor(page.Next),
+	}
+
+	// Page numbers are meaningless once the client follows cursors, so
+	// cursor pages only link forward.
+	if pag.Cursor != nil {
+		if page.Next != nil {
+			resp.Links.Next = lib.PageLink(r, map[string]string{"cursor": resp.NextCursor, "page": ""})
+		}
+		return resp
+	}
+
+	resp.Page = pag.Page
+
+	if pag.Page < resp.TotalPages {
+		resp.Links.Next = lib.PageLink(r, map[string]string{"page": strconv.Itoa(pag.Page + 1)})
+	}
+
+	if pag.Page > 1 {
+		resp.Links.Prev = lib.PageLink(r, map[string]string{"page": strconv.Itoa(pag.Page - 1)})
+	}
+
+	return resp
+}
+
+//	@Summary		Get song
+//	@Description	Get song by ID with all of its metadata and text
+//	@Tags			songs
+//	@Produce		json
+//	@Param			id				path		int		true	"Song ID"
+//	@Param			If-None-Match	header		string	false	"ETag of a previous response"
+//	@Success		200	{object}	types.Song
+//	@Success		304	"Not modified"
+//	@Failure		400	{object}	errs.APIError
+//	@Failure		404	{object}	errs.APIError
+//	@Failure		500	{string}	internal	server	error
+//	@Router			/songs/{id} [get]
+func (s *Server) handleGetSong(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidID()
+	}
+
+	song, err := s.srv.GetSong(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSONETag(w, r, http.StatusOK, song)
+}
+
+//	@Summary		Search songs by text
+//	@Description	Full-text search over song lyrics. Matching verses are returned as snippets with the found words wrapped in <b></b>
+//	@Tags			songs
+//	@Produce		json
+//	@Param			q		query		string	true	"Search query"				example(black hole)
+//	@Param			page	query		int		false	"Page number"				default(1)	example(1)
+//	@Param			size	query		int		false	"Number of songs per page"	default(10)	example(10)	Enums(10,25,50)
+//	@Success		200		{object}	types.SearchResults
+//	@Failure		400		{object}	errs.APIError
+//	@Failure		500		{string}	internal	server	error
+//	@Router			/songs/search [get]
+func (s *Server) handleSearchSongs(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	pag, err := lib.SongsPaginationValues(r)
+	if err != nil {
+		return err
+	}
+
+	results, err := s.srv.SearchSongs(ctx, pag, r.FormValue("q"))
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, types.SearchResults{Results: results})
 }
 
 //	@Summary		Get a list of verses by song
 //	@Description	Get a paginated list of verses by song
 //	@Tags			song
 //	@Produce		json
-//	@Param			id		query		int	true	"Song ID"
+//	@Param			id		path		int	true	"Song ID"
 //	@Param			page	query		int	false	"Page number"				default(1)	example(1)
 //	@Param			size	query		int	false	"Number of verses per page"	default(1)	example(1)	Enums(1,5,10)
 //	@Success		200		{object}	[]types.Text
 //	@Failure		400		{object}	errs.APIError
+//	@Failure		404		{object}	errs.APIError
 //	@Failure		500		{string}	internal	server	error
-//	@Router			/song [get]
+//	@Router			/songs/{id}/verses [get]
 func (s *Server) handleGetSongText(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	id, err := lib.ParseID(r)
+	id, err := lib.PathID(r)
 	if err != nil {
 		return errs.InvalidID()
 	}
@@ -99,21 +186,25 @@ func (s *Server) handleGetSongText(ctx context.Context, w http.ResponseWriter, r
 }
 
 //	@Summary		Delete song
-//	@Description	Delete song by ID
+//	@Description	Move song to the trash by ID. It can be restored until it is purged
 //	@Tags			song
 //	@Produce		json
-//	@Param			id	query		int	true	"Song ID"
+//	@Param			id			path		int		true	"Song ID"
+//	@Param			If-Match	header		string	false	"Only delete the song if its ETag matches"
+//	@Param			X-Author	header		string	false	"Author recorded in the song's revision"
 //	@Success		200	{object}	types.SongResponse
 //	@Failure		400	{object}	errs.APIError
+//	@Failure		404	{object}	errs.APIError
+//	@Failure		412	{object}	errs.APIError
 //	@Failure		500	{string}	internal	server	error
-//	@Router			/song [delete]
+//	@Router			/songs/{id} [delete]
 func (s *Server) handleDeleteSong(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	id, err := lib.ParseID(r)
+	id, err := lib.PathID(r)
 	if err != nil {
 		return errs.InvalidID()
 	}
 
-	if err := s.srv.DeleteSong(ctx, id); err != nil {
+	if err := s.srv.DeleteSong(ctx, id, lib.WriteOptionsValues(r)); err != nil {
 		return err
 	}
 
@@ -127,14 +218,20 @@ func (s *Server) handleDeleteSong(ctx context.Context, w http.ResponseWriter, r
 //	@Tags			song
 //	@Accept			json
 //	@Produce		json
-//	@Param			id		query		int						true	"Song ID"
+//	@Param			id		path		int						true	"Song ID"
 //	@Param			song	body		types.UpdateSongRequest	true	"Update song data"
+//	@Param			If-Match	header	string				false	"Only update the song if its ETag matches"
+//	@Param			X-Author	header	string				false	"Author recorded in the song's revision"
 //	@Success		200		{object}	[]types.SongResponse
+//	@Header			200		{string}	ETag	"Version of the updated song"
 //	@Failure		400		{object}	errs.APIError
+//	@Failure		404		{object}	errs.APIError
+//	@Failure		409		{object}	errs.APIError
+//	@Failure		412		{object}	errs.APIError
 //	@Failure		500		{string}	internal	server	error
-//	@Router			/song [put]
+//	@Router			/songs/{id} [put]
 func (s *Server) handleUpdateSong(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	id, err := lib.ParseID(r)
+	id, err := lib.PathID(r)
 	if err != nil {
 		return errs.InvalidID()
 	}
@@ -146,22 +243,75 @@ func (s *Server) handleUpdateSong(ctx context.Context, w http.ResponseWriter, r
 	}
 	defer r.Body.Close()
 
-	if err := s.srv.UpdateSong(ctx, id, req); err != nil {
+	song, err := s.srv.UpdateSong(ctx, id, req, lib.WriteOptionsValues(r))
+	if err != nil {
 		return err
 	}
 
+	w.Header().Set("ETag", lib.ETag(song))
+
 	resp := types.NewSongResponse(http.StatusOK, "song successfully updated")
 
 	return lib.WriteJSON(w, http.StatusOK, resp)
 }
 
+//	@Summary		Patch song
+//	@Description	Partially update song by ID with a JSON merge patch (RFC 7396), which must be a JSON object. Only the supplied fields are changed; null clears albumId or trackNumber
+//	@Tags			song
+//	@Accept			json
+//	@Accept			application/merge-patch+json
+//	@Produce		json
+//	@Param			id		path		int						true	"Song ID"
+//	@Param			song	body		types.PatchSongRequest	true	"Merge patch"
+//	@Param			If-Match	header	string				false	"Only patch the song if its ETag matches"
+//	@Param			X-Author	header	string				false	"Author recorded in the song's revision"
+//	@Success		200		{object}	types.Song
+//	@Failure		400		{object}	errs.APIError
+//	@Failure		404		{object}	errs.APIError
+//	@Failure		409		{object}	errs.APIError
+//	@Failure		412		{object}	errs.APIError
+//	@Failure		415		{object}	errs.APIError
+//	@Failure		500		{string}	internal	server	error
+//	@Router			/songs/{id} [patch]
+func (s *Server) handlePatchSong(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidID()
+	}
+
+	if !lib.IsMergePatch(r) {
+		return errs.UnsupportedMediaType()
+	}
+
+	req := new(types.PatchSongRequest)
+
+	if err := lib.DecodeMergePatch(r.Body, req); err != nil {
+		return errs.InvalidJSON()
+	}
+	defer r.Body.Close()
+
+	song, err := s.srv.PatchSong(ctx, id, req, lib.WriteOptionsValues(r))
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("ETag", lib.ETag(song))
+
+	return lib.WriteJSON(w, http.StatusOK, song)
+}
+
 //	@Summary		Add song
-//	@Description	Add song
+//	@Description	Add song. The song is stored right away and its release date, text and link are fetched in the background; follow the progress with GET /songs/{id}/enrichment. A song named like one already in the group, regardless of case and whitespace, is rejected with 409 and the ID of that song in existingId
 //	@Tags			songs
 //	@Accept			json
 //	@Produce		json
 //	@Param			song	body		types.SongRequest	true	"Song data"
+//	@Param			X-Author	header	string			false	"Author recorded in the song's revision"
+//	@Success		201		{object}	types.Song
+//	@Header			201		{string}	Location	"Path of the new song"
+//	@Header			201		{string}	ETag		"Version of the new song"
 //	@Failure		400		{object}	errs.APIError
+//	@Failure		409		{object}	errs.APIError
 //	@Failure		500		{string}	internal	server	error
 //	@Router			/songs [post]
 func (s *Server) handleAddSong(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
@@ -172,11 +322,13 @@ func (s *Server) handleAddSong(ctx context.Context, w http.ResponseWriter, r *ht
 	}
 	defer r.Body.Close()
 
-	if err := s.srv.AddSong(ctx, req); err != nil {
+	song, err := s.srv.AddSong(ctx, req, lib.WriteOptionsValues(r))
+	if err != nil {
 		return err
 	}
 
-	resp := types.NewSongResponse(http.StatusOK, "song successfully added")
+	w.Header().Set("Location", "/songs/"+strconv.Itoa(song.ID))
+	w.Header().Set("ETag", lib.ETag(song))
 
-	return lib.WriteJSON(w, http.StatusOK, resp)
+	return lib.WriteJSON(w, http.StatusCreated, song)
 }
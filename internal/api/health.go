@@ -0,0 +1,18 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/erknas/song-library/internal/lib"
+)
+
+//	@Summary		Health check
+//	@Description	Report whether the song details providers are up, with the circuit breaker state of the details API. The status is degraded while a provider is down; songs are still added then and enriched once it is back
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	types.Health
+//	@Router			/health [get]
+func (s *Server) handleGetHealth(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return lib.WriteJSON(w, http.StatusOK, s.srv.GetHealth(ctx))
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/erknas/song-library/internal/lib"
+)
+
+// importTimeout bounds a bulk import, including the calls to the details
+// API for rows that need enrichment.
+const importTimeout = time.Minute * 10
+
+// importReportTimeout is the time left after importTimeout to store the rows
+// that are ready and write the report.
+const importReportTimeout = time.Second * 10
+
+//	@Summary		Import songs
+//	@Description	Add songs in bulk from CSV with a header row or from JSON Lines. Each row has song and group and optionally releaseDate, text, link, albumId and trackNumber; rows without releaseDate are enriched through the details provider. Valid rows are added even if others fail or the import times out enriching them, and the report lists the outcome of every row by its line. A row naming a song that already exists, or a song of an earlier row, fails unless onConflict says to skip it or to update the song
+//	@Tags			songs
+//	@Accept			text/csv
+//	@Accept			application/x-ndjson
+//	@Produce		json
+//	@Param			songs		body	string	true	"CSV or JSON Lines file"
+//	@Param			onConflict	query	string	false	"What to do with songs that already exist"	Enums(error,skip,update)	default(error)
+//	@Param			X-Author	header	string	false	"Author recorded in the songs' revisions"
+//	@Success		200	{object}	types.ImportReport
+//	@Failure		400	{object}	errs.APIError
+//	@Failure		408	{object}	errs.APIError
+//	@Failure		413	{object}	errs.APIError
+//	@Failure		415	{object}	errs.APIError
+//	@Failure		500	{string}	internal	server	error
+//	@Router			/songs/import [post]
+func (s *Server) handleImportSongs(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	// The server timeouts are sized for regular requests.
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Now().Add(importTimeout))
+	rc.SetWriteDeadline(time.Now().Add(importTimeout + importReportTimeout))
+
+	rows, err := lib.ImportRows(w, r)
+	if err != nil {
+		return err
+	}
+
+	report, err := s.srv.ImportSongs(ctx, rows, r.URL.Query().Get("onConflict"), lib.WriteOptionsValues(r))
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/lib"
+	"github.com/erknas/song-library/internal/types"
+)
+
+//	@Summary		Get a list of song revisions
+//	@Description	Get a paginated list of the song's revisions, newest first. A revision is recorded whenever the song is added, updated, restored or deleted
+//	@Tags			revisions
+//	@Produce		json
+//	@Param			id		path		int	true	"Song ID"
+//	@Param			page	query		int	false	"Page number"					default(1)	example(1)
+//	@Param			size	query		int	false	"Number of revisions per page"	default(10)	example(10)	Enums(10,25,50)
+//	@Success		200		{object}	types.Revisions
+//	@Failure		400		{object}	errs.APIError
+//	@Failure		500		{string}	internal	server	error
+//	@Router			/songs/{id}/revisions [get]
+func (s *Server) handleGetSongRevisions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidID()
+	}
+
+	pag, err := lib.SongsPaginationValues(r)
+	if err != nil {
+		return err
+	}
+
+	revisions, err := s.srv.GetSongRevisions(ctx, id, pag)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, types.Revisions{Revisions: revisions})
+}
+
+//	@Summary		Get song revision
+//	@Description	Get a revision of the song by ID
+//	@Tags			revisions
+//	@Produce		json
+//	@Param			id	path		int	true	"Song ID"
+//	@Param			rev	path		int	true	"Revision ID"
+//	@Success		200	{object}	types.Revision
+//	@Failure		400	{object}	errs.APIError
+//	@Failure		404	{object}	errs.APIError
+//	@Failure		500	{string}	internal	server	error
+//	@Router			/songs/{id}/revisions/{rev} [get]
+func (s *Server) handleGetSongRevision(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidID()
+	}
+
+	revID, err := strconv.Atoi(r.PathValue("rev"))
+	if err != nil {
+		return errs.InvalidRevisionID()
+	}
+
+	rev, err := s.srv.GetSongRevision(ctx, id, revID)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, rev)
+}
+
+//	@Summary		Diff song revisions
+//	@Description	Compare two revisions of the song: the metadata fields that changed and the text verse by verse
+//	@Tags			revisions
+//	@Produce		json
+//	@Param			id		path		int	true	"Song ID"
+//	@Param			from	query		int	true	"Revision ID to compare from"
+//	@Param			to		query		int	true	"Revision ID to compare to"
+//	@Success		200		{object}	types.RevisionDiff
+//	@Failure		400		{object}	errs.APIError
+//	@Failure		404		{object}	errs.APIError
+//	@Failure		500		{string}	internal	server	error
+//	@Router			/songs/{id}/diff [get]
+func (s *Server) handleDiffSongRevisions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidID()
+	}
+
+	from, err := strconv.Atoi(r.FormValue("from"))
+	if err != nil {
+		return errs.InvalidRevisionID()
+	}
+
+	to, err := strconv.Atoi(r.FormValue("to"))
+	if err != nil {
+		return errs.InvalidRevisionID()
+	}
+
+	diff, err := s.srv.DiffSongRevisions(ctx, id, from, to)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, diff)
+}
+
+//	@Summary		Restore song revision
+//	@Description	Put the song back to the state of a revision. A deleted song is added again under its old ID
+//	@Tags			revisions
+//	@Produce		json
+//	@Param			id			path		int		true	"Song ID"
+//	@Param			rev			path		int		true	"Revision ID"
+//	@Param			If-Match	header		string	false	"Only restore if the song's ETag matches"
+//	@Param			X-Author	header		string	false	"Author recorded in the new revision"
+//	@Success		200			{object}	types.Song
+//	@Failure		400			{object}	errs.APIError
+//	@Failure		404			{object}	errs.APIError
+//	@Failure		409			{object}	errs.APIError
+//	@Failure		412			{object}	errs.APIError
+//	@Failure		500			{string}	internal	server	error
+//	@Router			/songs/{id}/revisions/{rev}/restore [post]
+func (s *Server) handleRestoreSongRevision(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidID()
+	}
+
+	revID, err := strconv.Atoi(r.PathValue("rev"))
+	if err != nil {
+		return errs.InvalidRevisionID()
+	}
+
+	song, err := s.srv.RestoreSongRevision(ctx, id, revID, lib.WriteOptionsValues(r))
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("ETag", lib.ETag(song))
+
+	return lib.WriteJSON(w, http.StatusOK, song)
+}
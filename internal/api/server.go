@@ -5,9 +5,8 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	_ "github.com/erknas/song-library/docs"
@@ -48,9 +47,6 @@ func (s *Server) Start(ctx context.Context, cfg *config.Config) {
 		IdleTimeout:  cfg.IdleTimeout,
 	}
 
-	quitch := make(chan os.Signal, 1)
-	signal.Notify(quitch, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
-
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			s.log.Error("failed to start server", "error", err)
@@ -60,9 +56,9 @@ func (s *Server) Start(ctx context.Context, cfg *config.Config) {
 
 	s.log.Info("starting server", "port", srv.Addr, "addr", fmt.Sprintf("http://localhost%s", srv.Addr))
 
-	<-quitch
+	<-ctx.Done()
 
-	shutdownCtx, cancel := context.WithTimeout(ctx, ctxTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), ctxTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(shutdownCtx); err != nil {
@@ -74,8 +70,69 @@ func (s *Server) Start(ctx context.Context, cfg *config.Config) {
 }
 
 func (s *Server) registerRoutes(router *http.ServeMux) {
-	router.HandleFunc("/songs", lib.MakeHTTPFunc(s.handleSongs))
-	router.HandleFunc("/song", lib.MakeHTTPFunc(s.handleSong))
+	router.HandleFunc("GET /health", lib.MakeHTTPFunc(s.handleGetHealth))
+	router.HandleFunc("DELETE /admin/details-cache", lib.MakeHTTPFunc(s.handleInvalidateDetailsCache))
+	router.HandleFunc("GET /songs", lib.MakeHTTPFunc(s.handleGetSongs))
+	router.HandleFunc("POST /songs", lib.MakeHTTPFunc(s.handleAddSong))
+	router.HandleFunc("POST /songs/import", lib.MakeHTTPFuncTimeout(s.handleImportSongs, importTimeout))
+	router.HandleFunc("GET /songs/export", lib.MakeHTTPFuncTimeout(s.handleExportSongs, exportTimeout))
+	router.HandleFunc("GET /songs/search", lib.MakeHTTPFunc(s.handleSearchSongs))
+	router.HandleFunc("GET /songs/trash", lib.MakeHTTPFunc(s.handleGetTrash))
+	router.HandleFunc("GET /songs/enrichment", lib.MakeHTTPFunc(s.handleGetEnrichmentJobs))
+	router.HandleFunc("GET /songs/{id}", lib.MakeHTTPFunc(s.handleGetSong))
+	router.HandleFunc("PUT /songs/{id}", lib.MakeHTTPFunc(s.handleUpdateSong))
+	router.HandleFunc("PATCH /songs/{id}", lib.MakeHTTPFunc(s.handlePatchSong))
+	router.HandleFunc("DELETE /songs/{id}", lib.MakeHTTPFunc(s.handleDeleteSong))
+	router.HandleFunc("GET /songs/{id}/verses", lib.MakeHTTPFunc(s.handleGetSongText))
+	router.HandleFunc("POST /songs/{id}/restore", lib.MakeHTTPFunc(s.handleRestoreSong))
+	router.HandleFunc("GET /songs/{id}/enrichment", lib.MakeHTTPFunc(s.handleGetSongEnrichment))
+	router.HandleFunc("POST /songs/{id}/enrichment/retry", lib.MakeHTTPFunc(s.handleRetrySongEnrichment))
+	router.HandleFunc("GET /songs/{id}/revisions", lib.MakeHTTPFunc(s.handleGetSongRevisions))
+	router.HandleFunc("GET /songs/{id}/revisions/{rev}", lib.MakeHTTPFunc(s.handleGetSongRevision))
+	router.HandleFunc("POST /songs/{id}/revisions/{rev}/restore", lib.MakeHTTPFunc(s.handleRestoreSongRevision))
+	router.HandleFunc("GET /songs/{id}/diff", lib.MakeHTTPFunc(s.handleDiffSongRevisions))
+
+	router.HandleFunc("GET /groups", lib.MakeHTTPFunc(s.handleGetGroups))
+	router.HandleFunc("POST /groups", lib.MakeHTTPFunc(s.handleAddGroup))
+	router.HandleFunc("GET /groups/{id}", lib.MakeHTTPFunc(s.handleGetGroup))
+	router.HandleFunc("PUT /groups/{id}", lib.MakeHTTPFunc(s.handleUpdateGroup))
+	router.HandleFunc("DELETE /groups/{id}", lib.MakeHTTPFunc(s.handleDeleteGroup))
+	router.HandleFunc("GET /groups/{id}/songs", lib.MakeHTTPFunc(s.handleGetGroupSongs))
+
+	router.HandleFunc("GET /albums", lib.MakeHTTPFunc(s.handleGetAlbums))
+	router.HandleFunc("POST /albums", lib.MakeHTTPFunc(s.handleAddAlbum))
+	router.HandleFunc("GET /albums/{id}", lib.MakeHTTPFunc(s.handleGetAlbum))
+	router.HandleFunc("PUT /albums/{id}", lib.MakeHTTPFunc(s.handleUpdateAlbum))
+	router.HandleFunc("DELETE /albums/{id}", lib.MakeHTTPFunc(s.handleDeleteAlbum))
+	router.HandleFunc("GET /albums/{id}/songs", lib.MakeHTTPFunc(s.handleGetAlbumTracks))
+
+	// Legacy routes taking the song ID as a query parameter.
+	router.HandleFunc("GET /song", deprecated("/verses", lib.MakeHTTPFunc(s.handleGetSongText)))
+	router.HandleFunc("PUT /song", deprecated("", lib.MakeHTTPFunc(s.handleUpdateSong)))
+	router.HandleFunc("DELETE /song", deprecated("", lib.MakeHTTPFunc(s.handleDeleteSong)))
 
 	router.Handle("/swagger/", httpSwagger.WrapHandler)
 }
+
+// deprecated marks the responses of a legacy /song route with the
+// Deprecation header and links them to /songs/{id} followed by suffix.
+// The id query parameter is exposed as the {id} path value, so the
+// handlers of the legacy routes are the ones of their successors.
+func deprecated(suffix string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		r.SetPathValue("id", q.Get("id"))
+
+		successor := url.URL{
+			Path: "/songs/" + url.PathEscape(q.Get("id")) + suffix,
+		}
+
+		q.Del("id")
+		successor.RawQuery = q.Encode()
+
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor.String()))
+
+		next(w, r)
+	}
+}
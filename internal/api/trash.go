@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/lib"
+	"github.com/erknas/song-library/internal/types"
+)
+
+//	@Summary		Get a list of deleted songs
+//	@Description	Get a paginated list of the songs in the trash, most recently deleted first. Songs are purged from the trash after the retention period
+//	@Tags			trash
+//	@Produce		json
+//	@Param			page	query		int	false	"Page number"				default(1)	example(1)
+//	@Param			size	query		int	false	"Number of songs per page"	default(10)	example(10)	Enums(10,25,50)
+//	@Success		200		{object}	types.Trash
+//	@Failure		400		{object}	errs.APIError
+//	@Failure		500		{string}	internal	server	error
+//	@Router			/songs/trash [get]
+func (s *Server) handleGetTrash(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	pag, err := lib.SongsPaginationValues(r)
+	if err != nil {
+		return err
+	}
+
+	songs, err := s.srv.GetTrash(ctx, pag)
+	if err != nil {
+		return err
+	}
+
+	return lib.WriteJSON(w, http.StatusOK, types.Trash{Songs: songs})
+}
+
+//	@Summary		Restore deleted song
+//	@Description	Take the song out of the trash
+//	@Tags			trash
+//	@Produce		json
+//	@Param			id			path		int		true	"Song ID"
+//	@Param			X-Author	header		string	false	"Author recorded in the song's revision"
+//	@Success		200			{object}	types.Song
+//	@Failure		400			{object}	errs.APIError
+//	@Failure		404			{object}	errs.APIError
+//	@Failure		409			{object}	errs.APIError
+//	@Failure		500			{string}	internal	server	error
+//	@Router			/songs/{id}/restore [post]
+func (s *Server) handleRestoreSong(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id, err := lib.PathID(r)
+	if err != nil {
+		return errs.InvalidID()
+	}
+
+	song, err := s.srv.RestoreSong(ctx, id, lib.WriteOptionsValues(r))
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("ETag", lib.ETag(song))
+
+	return lib.WriteJSON(w, http.StatusOK, song)
+}
@@ -8,18 +8,33 @@ import (
 	"github.com/joho/godotenv"
 )
 
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+
+	DetailsHTTP = "http"
+	DetailsFile = "file"
+
+	DetailsCacheNone     = "none"
+	DetailsCacheMemory   = "memory"
+	DetailsCachePostgres = "postgres"
+)
+
 type Config struct {
-	Env string `env:"ENV"`
+	Env     string `env:"ENV"`
+	Storage string `env:"STORAGE" env-default:"postgres"`
 	ServerConifg
 	PostgresConfig
+	TrashConfig
+	EnrichmentConfig
+	DetailsConfig
 }
 
 type ServerConifg struct {
-	Addr             string        `env:"ADDR"`
-	ReadTimeout      time.Duration `env:"READ_TIMEOUT"`
-	WriteTimeout     time.Duration `env:"WRITE_TIMEOUT"`
-	IdleTimeout      time.Duration `env:"IDLE_TIMEOUT"`
-	ThirdPartyAPIURL string        `env:"THIRD_PARTY_API_URL"`
+	Addr         string        `env:"ADDR"`
+	ReadTimeout  time.Duration `env:"READ_TIMEOUT"`
+	WriteTimeout time.Duration `env:"WRITE_TIMEOUT"`
+	IdleTimeout  time.Duration `env:"IDLE_TIMEOUT"`
 }
 
 type PostgresConfig struct {
@@ -31,6 +46,52 @@ type PostgresConfig struct {
 	MigrationPath string `env:"MIGRATIONS_PATH"`
 }
 
+// TrashConfig controls how long deleted songs stay in the trash before the
+// purge job deletes them for good. A zero TrashPurgeInterval disables the job.
+type TrashConfig struct {
+	TrashRetention     time.Duration `env:"TRASH_RETENTION" env-default:"720h"`
+	TrashPurgeInterval time.Duration `env:"TRASH_PURGE_INTERVAL" env-default:"1h"`
+}
+
+// EnrichmentConfig controls the workers fetching song details in the
+// background. A failed attempt is retried after EnrichmentRetryDelay, doubled
+// on every further failure, until EnrichmentMaxAttempts. A zero
+// EnrichmentWorkers or EnrichmentPollInterval disables enrichment.
+type EnrichmentConfig struct {
+	EnrichmentWorkers      int           `env:"ENRICHMENT_WORKERS" env-default:"4"`
+	EnrichmentMaxAttempts  int           `env:"ENRICHMENT_MAX_ATTEMPTS" env-default:"5"`
+	EnrichmentRetryDelay   time.Duration `env:"ENRICHMENT_RETRY_DELAY" env-default:"30s"`
+	EnrichmentPollInterval time.Duration `env:"ENRICHMENT_POLL_INTERVAL" env-default:"5s"`
+}
+
+// DetailsConfig selects where song details come from. DetailsProviders are
+// tried in order until one has the details: http asks ThirdPartyAPIURL and
+// file looks them up in the JSON file at DetailsFile.
+//
+// A call to the API times out after DetailsTimeout and is retried up to
+// DetailsRetries times, waiting DetailsRetryDelay doubled on every retry but
+// no more than DetailsMaxRetryDelay. After DetailsBreakerThreshold failed
+// lookups in a row the API isn't called for DetailsBreakerCooldown.
+//
+// Lookups in the API are cached in DetailsCache, in process or in Postgres,
+// for DetailsCacheTTL, or DetailsCacheNegativeTTL for songs it has no
+// details for. The cache holds up to DetailsCacheSize entries.
+type DetailsConfig struct {
+	DetailsProviders        []string      `env:"DETAILS_PROVIDERS" env-default:"http" env-separator:","`
+	ThirdPartyAPIURL        string        `env:"THIRD_PARTY_API_URL"`
+	DetailsFile             string        `env:"DETAILS_FILE"`
+	DetailsTimeout          time.Duration `env:"DETAILS_TIMEOUT" env-default:"3s"`
+	DetailsRetries          int           `env:"DETAILS_RETRIES" env-default:"2"`
+	DetailsRetryDelay       time.Duration `env:"DETAILS_RETRY_DELAY" env-default:"200ms"`
+	DetailsMaxRetryDelay    time.Duration `env:"DETAILS_MAX_RETRY_DELAY" env-default:"2s"`
+	DetailsBreakerThreshold int           `env:"DETAILS_BREAKER_THRESHOLD" env-default:"5"`
+	DetailsBreakerCooldown  time.Duration `env:"DETAILS_BREAKER_COOLDOWN" env-default:"30s"`
+	DetailsCache            string        `env:"DETAILS_CACHE" env-default:"memory"`
+	DetailsCacheSize        int           `env:"DETAILS_CACHE_SIZE" env-default:"10000"`
+	DetailsCacheTTL         time.Duration `env:"DETAILS_CACHE_TTL" env-default:"24h"`
+	DetailsCacheNegativeTTL time.Duration `env:"DETAILS_CACHE_NEGATIVE_TTL" env-default:"1h"`
+}
+
 func Load() *Config {
 	if err := godotenv.Load(); err != nil {
 		log.Fatalf("failed to load .env file: %s", err)
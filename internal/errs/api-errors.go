@@ -8,6 +8,8 @@ import (
 type APIError struct {
 	StatusCode int `json:"statusCode"`
 	Msg        any `json:"msg"`
+	// ExistingID is the ID of the song a conflicting request clashes with.
+	ExistingID int `json:"existingId,omitempty"`
 }
 
 func (e APIError) Error() string {
@@ -29,6 +31,70 @@ func InvalidID() APIError {
 	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid song ID"))
 }
 
+func EmptySongName() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("song name is empty"))
+}
+
+func NullField(field string) APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("%s can't be null", field))
+}
+
+func UnsupportedMediaType() APIError {
+	return NewAPIError(http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type, expected application/merge-patch+json"))
+}
+
+func FieldTooLong(field string, max int) APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("%s is longer than %d characters", field, max))
+}
+
+func UnsupportedImportType() APIError {
+	return NewAPIError(http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type, expected text/csv or application/x-ndjson"))
+}
+
+func InvalidImport(line int, err error) APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid import file on line %d: %w", line, err))
+}
+
+func MissingImportColumn(column string) APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("import file has no %s column", column))
+}
+
+func EmptyImport() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("import file has no songs"))
+}
+
+func ImportTooLarge(limit int64) APIError {
+	return NewAPIError(http.StatusRequestEntityTooLarge, fmt.Errorf("import file is larger than %d bytes", limit))
+}
+
+func TooManyImportRows(max int) APIError {
+	return NewAPIError(http.StatusRequestEntityTooLarge, fmt.Errorf("import file has more than %d songs", max))
+}
+
+func InvalidExportFormat() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid export format, expected json, csv or ndjson"))
+}
+
+func InvalidGroupID() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid group ID"))
+}
+
+func EmptyGroupName() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("group name is empty"))
+}
+
+func InvalidAlbumID() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid album ID"))
+}
+
+func EmptyAlbumTitle() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("album title is empty"))
+}
+
+func InvalidTrackNumber() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("track number must be positive and requires an album"))
+}
+
 func InvalidPage() APIError {
 	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid page"))
 }
@@ -37,10 +103,42 @@ func InvalidPageSize() APIError {
 	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid page size"))
 }
 
+func InvalidCursor() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid cursor"))
+}
+
 func InvalidDate() APIError {
 	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid date format"))
 }
 
+func InvalidYear() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid year"))
+}
+
+func InvalidDecade() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid decade, expected a year divisible by 10 such as 1990 or 1990s"))
+}
+
+func InvalidDateRange() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid date range, start date is after end date"))
+}
+
+func InvalidQuery() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid query string"))
+}
+
+func InvalidSort(field string) APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid sort field %q, expected id, song, group or releaseDate", field))
+}
+
+func InvalidMatch() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid match mode, expected exact, prefix or fuzzy"))
+}
+
+func EmptySearchQuery() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("search query is empty"))
+}
+
 func EndOfText() APIError {
 	return NewAPIError(http.StatusBadRequest, fmt.Errorf("end of song text"))
 }
@@ -53,6 +151,97 @@ func NoSongs() APIError {
 	return NewAPIError(http.StatusBadRequest, fmt.Errorf("songs not found"))
 }
 
+func NotFound() APIError {
+	return NewAPIError(http.StatusNotFound, fmt.Errorf("not found"))
+}
+
+func SongNotFound() APIError {
+	return NewAPIError(http.StatusNotFound, fmt.Errorf("song not found"))
+}
+
+func SongNotInTrash() APIError {
+	return NewAPIError(http.StatusNotFound, fmt.Errorf("song not found in trash"))
+}
+
+func InvalidRevisionID() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid revision ID"))
+}
+
+func NoRevisions() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("revisions not found"))
+}
+
+func RevisionNotFound() APIError {
+	return NewAPIError(http.StatusNotFound, fmt.Errorf("revision not found"))
+}
+
+func InvalidEnrichmentStatus() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid enrichment status, expected pending or failed"))
+}
+
+func SongEnriched() APIError {
+	return NewAPIError(http.StatusConflict, fmt.Errorf("song is already enriched, only pending or failed songs can be retried"))
+}
+
+func NoEnrichmentJobs() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("enrichment jobs not found"))
+}
+
+func InvalidCacheKey() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("song and group are both required to invalidate a single entry"))
+}
+
+func DetailsCacheDisabled() APIError {
+	return NewAPIError(http.StatusNotFound, fmt.Errorf("details cache is disabled"))
+}
+
+func NoGroups() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("groups not found"))
+}
+
+func GroupNotFound() APIError {
+	return NewAPIError(http.StatusNotFound, fmt.Errorf("group not found"))
+}
+
+func GroupAlreadyExists() APIError {
+	return NewAPIError(http.StatusConflict, fmt.Errorf("group already exists"))
+}
+
+func GroupHasSongs() APIError {
+	return NewAPIError(http.StatusConflict, fmt.Errorf("group still has songs or albums"))
+}
+
+func NoAlbums() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("albums not found"))
+}
+
+func AlbumNotFound() APIError {
+	return NewAPIError(http.StatusNotFound, fmt.Errorf("album not found"))
+}
+
+func AlbumHasSongs() APIError {
+	return NewAPIError(http.StatusConflict, fmt.Errorf("album still has songs"))
+}
+
+func TrackNumberTaken() APIError {
+	return NewAPIError(http.StatusConflict, fmt.Errorf("track number is already taken on this album"))
+}
+
+// SongAlreadyExists reports a song with the name and group of the song id.
+func SongAlreadyExists(id int) APIError {
+	err := NewAPIError(http.StatusConflict, fmt.Errorf("song already exists"))
+	err.ExistingID = id
+	return err
+}
+
+func InvalidOnConflict() APIError {
+	return NewAPIError(http.StatusBadRequest, fmt.Errorf("invalid onConflict, expected error, skip or update"))
+}
+
+func PreconditionFailed() APIError {
+	return NewAPIError(http.StatusPreconditionFailed, fmt.Errorf("song was modified, get the current version and retry"))
+}
+
 func APICallTimeout() APIError {
 	return NewAPIError(http.StatusRequestTimeout, fmt.Errorf("request timeout"))
 }
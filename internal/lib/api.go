@@ -1,15 +1,21 @@
 package lib
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/erknas/song-library/internal/errs"
 	"github.com/erknas/song-library/internal/logger"
+	"github.com/erknas/song-library/internal/storage"
 	"github.com/erknas/song-library/internal/types"
 )
 
@@ -18,16 +24,29 @@ const Layout = "02.01.2006"
 type APIFunc func(context.Context, http.ResponseWriter, *http.Request) error
 
 func MakeHTTPFunc(fn APIFunc) http.HandlerFunc {
+	return MakeHTTPFuncTimeout(fn, time.Second*3)
+}
+
+// MakeHTTPFuncTimeout is MakeHTTPFunc for handlers that need more time than
+// a regular request, such as bulk imports.
+func MakeHTTPFuncTimeout(fn APIFunc, timeout time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancel := context.WithTimeout(r.Context(), time.Second*3)
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
 		defer cancel()
 
 		ctx = logger.WithRequestID(ctx)
 
 		if err := fn(ctx, w, r); err != nil {
-			if apiErr, ok := err.(errs.APIError); ok {
+			var apiErr errs.APIError
+			switch {
+			case errors.As(err, &apiErr):
+				WriteJSON(w, apiErr.StatusCode, apiErr)
+			// The service maps missing records to its own errors, this
+			// only catches the ones that slip through.
+			case errors.Is(err, storage.ErrNotFound):
+				apiErr = errs.NotFound()
 				WriteJSON(w, apiErr.StatusCode, apiErr)
-			} else {
+			default:
 				errResp := map[string]any{
 					"statusCode": http.StatusInternalServerError,
 					"msg":        "internal server error",
@@ -44,9 +63,68 @@ func WriteJSON(w http.ResponseWriter, status int, v any) error {
 	return json.NewEncoder(w).Encode(v)
 }
 
-func ParseID(r *http.Request) (int, error) {
-	id := r.FormValue("id")
-	return strconv.Atoi(id)
+// PageLink returns the path and query of r with params replaced. Empty
+// values remove the parameter.
+func PageLink(r *http.Request, params map[string]string) string {
+	q := r.URL.Query()
+
+	for k, v := range params {
+		if len(v) == 0 {
+			q.Del(k)
+			continue
+		}
+		q.Set(k, v)
+	}
+
+	u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+
+	return u.String()
+}
+
+// PathID parses the {id} wildcard of the matched route pattern.
+func PathID(r *http.Request) (int, error) {
+	return strconv.Atoi(r.PathValue("id"))
+}
+
+// WriteOptionsValues reads the preconditions and the author of a song
+// change from the request headers.
+func WriteOptionsValues(r *http.Request) types.WriteOptions {
+	return types.WriteOptions{
+		IfMatch: r.Header.Get("If-Match"),
+		Author:  strings.TrimSpace(r.Header.Get("X-Author")),
+	}
+}
+
+// IsMergePatch reports whether the body of r is a JSON merge patch. Plain
+// JSON and a missing Content-Type are accepted as well.
+func IsMergePatch(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/merge-patch+json" || mediaType == "application/json"
+}
+
+// DecodeMergePatch decodes the JSON merge patch in body into v. Only a JSON
+// object patches members; null, arrays and scalars would replace the whole
+// resource, so they are rejected.
+func DecodeMergePatch(body io.Reader, v any) error {
+	var raw json.RawMessage
+	if err := json.NewDecoder(body).Decode(&raw); err != nil {
+		return err
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		return errors.New("merge patch is not a JSON object")
+	}
+
+	return json.Unmarshal(raw, v)
 }
 
 func ParseURL(lurl string, req *types.SongRequest) (string, error) {
@@ -132,19 +210,80 @@ func TextPaginationValues(r *http.Request) (types.Pagination, error) {
 	return pagination, nil
 }
 
+// SortValues parses the sort parameter: a comma-separated list of fields,
+// each optionally prefixed with "-" for descending order. The parameter
+// may also be repeated, e.g. sort=-releaseDate&sort=song.
+func SortValues(r *http.Request) ([]types.SortField, error) {
+	// r.FormValue swallows parse errors once the form has been parsed, so
+	// the query is parsed again to reject malformed ones.
+	query, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		return nil, errs.InvalidQuery()
+	}
+
+	var (
+		fields []types.SortField
+		seen   = make(map[string]bool)
+	)
+
+	for _, param := range query["sort"] {
+		for _, name := range strings.Split(param, ",") {
+			name = strings.TrimSpace(name)
+			if len(name) == 0 {
+				continue
+			}
+
+			field := types.SortField{Field: name}
+
+			switch name[0] {
+			case '-':
+				field = types.SortField{Field: name[1:], Desc: true}
+			case '+':
+				field = types.SortField{Field: name[1:]}
+			}
+
+			if !types.SortFields[field.Field] || seen[field.Field] {
+				return nil, errs.InvalidSort(name)
+			}
+
+			seen[field.Field] = true
+			fields = append(fields, field)
+		}
+	}
+
+	return fields, nil
+}
+
 func FilterValues(r *http.Request) (types.Filter, error) {
 	var (
 		song    = r.FormValue("song")
 		group   = r.FormValue("group")
 		strDate = r.FormValue("date")
+		match   = r.FormValue("match")
 	)
 
+	switch match {
+	case "":
+		match = types.MatchExact
+	case types.MatchExact, types.MatchPrefix, types.MatchFuzzy:
+	default:
+		return types.Filter{}, errs.InvalidMatch()
+	}
+
+	from, to, err := dateRange(r)
+	if err != nil {
+		return types.Filter{}, err
+	}
+
+	filters := types.Filter{
+		Song:     song,
+		Group:    group,
+		DateFrom: from,
+		DateTo:   to,
+		Match:    match,
+	}
+
 	if len(strDate) == 0 {
-		filters := types.Filter{
-			Song:  song,
-			Group: group,
-			Date:  nil,
-		}
 		return filters, nil
 	}
 
@@ -153,11 +292,69 @@ func FilterValues(r *http.Request) (types.Filter, error) {
 		return types.Filter{}, errs.InvalidDate()
 	}
 
-	filters := types.Filter{
-		Song:  song,
-		Group: group,
-		Date:  &date,
-	}
+	filters.Date = &date
 
 	return filters, nil
 }
+
+// dateRange combines dateFrom, dateTo, year and decade into one inclusive
+// range. Every supplied bound narrows the range, so year=1995&decade=1990s
+// is the same as year=1995.
+func dateRange(r *http.Request) (*time.Time, *time.Time, error) {
+	var from, to *time.Time
+
+	narrow := func(lo, hi *time.Time) {
+		if lo != nil && (from == nil || lo.After(*from)) {
+			from = lo
+		}
+		if hi != nil && (to == nil || hi.Before(*to)) {
+			to = hi
+		}
+	}
+
+	if s := r.FormValue("dateFrom"); len(s) != 0 {
+		d, err := time.Parse(Layout, s)
+		if err != nil {
+			return nil, nil, errs.InvalidDate()
+		}
+		narrow(&d, nil)
+	}
+
+	if s := r.FormValue("dateTo"); len(s) != 0 {
+		d, err := time.Parse(Layout, s)
+		if err != nil {
+			return nil, nil, errs.InvalidDate()
+		}
+		narrow(nil, &d)
+	}
+
+	if s := r.FormValue("year"); len(s) != 0 {
+		year, err := strconv.Atoi(s)
+		if err != nil || year < 1 || year > 9999 {
+			return nil, nil, errs.InvalidYear()
+		}
+		lo, hi := yearsRange(year, 1)
+		narrow(&lo, &hi)
+	}
+
+	if s := r.FormValue("decade"); len(s) != 0 {
+		decade, err := strconv.Atoi(strings.TrimSuffix(s, "s"))
+		if err != nil || decade < 0 || decade > 9990 || decade%10 != 0 {
+			return nil, nil, errs.InvalidDecade()
+		}
+		lo, hi := yearsRange(decade, 10)
+		narrow(&lo, &hi)
+	}
+
+	if from != nil && to != nil && from.After(*to) {
+		return nil, nil, errs.InvalidDateRange()
+	}
+
+	return from, to, nil
+}
+
+func yearsRange(year, n int) (time.Time, time.Time) {
+	lo := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	hi := time.Date(year+n-1, time.December, 31, 0, 0, 0, 0, time.UTC)
+	return lo, hi
+}
@@ -0,0 +1,189 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/storage"
+	"github.com/erknas/song-library/internal/types"
+)
+
+func day(s string) *time.Time {
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return &d
+}
+
+func sameDay(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestFilterValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantFrom *time.Time
+		wantTo   *time.Time
+		wantErr  error
+	}{
+		{"none", "", nil, nil, nil},
+		{"date from", "dateFrom=01.02.2003", day("2003-02-01"), nil, nil},
+		{"date to", "dateTo=31.12.1999", nil, day("1999-12-31"), nil},
+		{"year", "year=1995", day("1995-01-01"), day("1995-12-31"), nil},
+		{"decade", "decade=1990", day("1990-01-01"), day("1999-12-31"), nil},
+		{"decade with suffix", "decade=1990s", day("1990-01-01"), day("1999-12-31"), nil},
+		{"year narrows decade", "year=1995&decade=1990s", day("1995-01-01"), day("1995-12-31"), nil},
+		{"date from narrows year", "year=1995&dateFrom=01.06.1995", day("1995-06-01"), day("1995-12-31"), nil},
+		{"date to narrows decade", "decade=2000&dateTo=31.12.2004", day("2000-01-01"), day("2004-12-31"), nil},
+		{"invalid date from", "dateFrom=2003-02-01", nil, nil, errs.InvalidDate()},
+		{"invalid date to", "dateTo=tomorrow", nil, nil, errs.InvalidDate()},
+		{"invalid year", "year=nineties", nil, nil, errs.InvalidYear()},
+		{"year zero", "year=0", nil, nil, errs.InvalidYear()},
+		{"year too large", "year=10000", nil, nil, errs.InvalidYear()},
+		{"decade not divisible by 10", "decade=1995", nil, nil, errs.InvalidDecade()},
+		{"negative decade", "decade=-10", nil, nil, errs.InvalidDecade()},
+		{"decade too large", "decade=10000", nil, nil, errs.InvalidDecade()},
+		{"year outside decade", "year=1985&decade=1990s", nil, nil, errs.InvalidDateRange()},
+		{"from after to", "dateFrom=02.01.2000&dateTo=01.01.2000", nil, nil, errs.InvalidDateRange()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/songs?"+tt.query, nil)
+
+			filter, err := FilterValues(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FilterValues() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if !sameDay(filter.DateFrom, tt.wantFrom) || !sameDay(filter.DateTo, tt.wantTo) {
+				t.Errorf("FilterValues() range = %v..%v, want %v..%v", filter.DateFrom, filter.DateTo, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestSortValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    []types.SortField
+		wantErr error
+	}{
+		{"none", "", nil, nil},
+		{"ascending", "sort=song", []types.SortField{{Field: types.SortBySong}}, nil},
+		{"descending", "sort=-releaseDate", []types.SortField{{Field: types.SortByReleaseDate, Desc: true}}, nil},
+		{"explicit ascending", "sort=%2Bgroup", []types.SortField{{Field: types.SortByGroup}}, nil},
+		{"comma separated", "sort=group,-releaseDate", []types.SortField{
+			{Field: types.SortByGroup},
+			{Field: types.SortByReleaseDate, Desc: true},
+		}, nil},
+		{"repeated parameter", "sort=group&sort=-id", []types.SortField{
+			{Field: types.SortByGroup},
+			{Field: types.SortByID, Desc: true},
+		}, nil},
+		{"blanks skipped", "sort=+song+,,", []types.SortField{{Field: types.SortBySong}}, nil},
+		{"unknown field", "sort=title", nil, errs.InvalidSort("title")},
+		{"unknown descending field", "sort=-text", nil, errs.InvalidSort("-text")},
+		{"double prefix", "sort=--song", nil, errs.InvalidSort("--song")},
+		{"duplicate field", "sort=song,-song", nil, errs.InvalidSort("-song")},
+		{"malformed query", "sort=%zz", nil, errs.InvalidQuery()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/songs?"+tt.query, nil)
+
+			got, err := SortValues(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SortValues() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SortValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeHTTPFunc(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"ok", nil, http.StatusOK},
+		{"api error", errs.SongNotFound(), http.StatusNotFound},
+		{"wrapped api error", fmt.Errorf("get song: %w", errs.InvalidID()), http.StatusBadRequest},
+		{"storage not found", fmt.Errorf("get song: %w", storage.NotFound("song", 1)), http.StatusNotFound},
+		{"other error", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := MakeHTTPFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return tt.err
+			})
+
+			w := httptest.NewRecorder()
+			h(w, httptest.NewRequest("GET", "/songs/1", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestDecodeMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantErr  bool
+		wantText string
+	}{
+		{"object", `{"text": "They will not force us"}`, false, "They will not force us"},
+		{"empty object", `{}`, false, ""},
+		{"leading whitespace", " \n\t{\"text\": \"x\"}", false, "x"},
+		{"null", `null`, true, ""},
+		{"array", `[{"text": "x"}]`, true, ""},
+		{"string", `"x"`, true, ""},
+		{"number", `42`, true, ""},
+		{"empty", ``, true, ""},
+		{"malformed", `{"text":`, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req types.PatchSongRequest
+
+			err := DecodeMergePatch(strings.NewReader(tt.body), &req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeMergePatch(%s) error = %v, want error %v", tt.body, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if req.Text.Set && *req.Text.Value != tt.wantText {
+				t.Errorf("text = %q, want %q", *req.Text.Value, tt.wantText)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package lib
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/types"
+)
+
+// CursorValue parses the cursor parameter. Without it songs are paginated
+// by page number.
+func CursorValue(r *http.Request) (*types.Cursor, error) {
+	cursor, err := DecodeCursor(r.FormValue("cursor"))
+	if err != nil {
+		return nil, errs.InvalidCursor()
+	}
+
+	return cursor, nil
+}
+
+// EncodeCursor turns c into the opaque string handed out to clients as
+// nextCursor. A nil cursor encodes to an empty string.
+func EncodeCursor(c *types.Cursor) string {
+	if c == nil {
+		return ""
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a cursor produced by EncodeCursor. An empty string
+// means no cursor.
+func DecodeCursor(s string) (*types.Cursor, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	c := new(types.Cursor)
+
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
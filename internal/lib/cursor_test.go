@@ -0,0 +1,97 @@
+package lib
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/types"
+)
+
+func sameCursor(a, b *types.Cursor) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ac, bc := *a, *b
+	ac.ReleaseDate, bc.ReleaseDate = nil, nil
+	return ac == bc && sameDay(a.ReleaseDate, b.ReleaseDate)
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor *types.Cursor
+	}{
+		{"id", &types.Cursor{Sort: "id", ID: 7}},
+		{"song", &types.Cursor{Sort: "song,id", ID: 3, Song: "Starlight"}},
+		{"group and date", &types.Cursor{
+			Sort:        "group,-releaseDate,id",
+			ID:          12,
+			Group:       "Muse",
+			ReleaseDate: day("2006-07-16"),
+		}},
+		{"undated", &types.Cursor{Sort: "releaseDate,id", ID: 6}},
+		{"rank", &types.Cursor{Sort: "-rank,id", ID: 5, Rank: 0.42}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := EncodeCursor(tt.cursor)
+			if s == "" {
+				t.Fatal("EncodeCursor() = empty string")
+			}
+			if url.QueryEscape(s) != s {
+				t.Errorf("EncodeCursor() = %q, want a URL-safe string", s)
+			}
+
+			got, err := DecodeCursor(s)
+			if err != nil {
+				t.Fatalf("DecodeCursor() error = %v", err)
+			}
+			if !sameCursor(got, tt.cursor) {
+				t.Errorf("DecodeCursor() = %+v, want %+v", got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestCursorValue(t *testing.T) {
+	valid := EncodeCursor(&types.Cursor{Sort: "id", ID: 2})
+
+	tests := []struct {
+		name    string
+		cursor  string
+		want    *types.Cursor
+		wantErr error
+	}{
+		{"missing", "", nil, nil},
+		{"valid", valid, &types.Cursor{Sort: "id", ID: 2}, nil},
+		{"not base64", "not a cursor!", nil, errs.InvalidCursor()},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"s":"id","id":2}`)), nil, errs.InvalidCursor()},
+		{"not JSON", base64.RawURLEncoding.EncodeToString([]byte("id=2")), nil, errs.InvalidCursor()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/songs?cursor="+url.QueryEscape(tt.cursor), nil)
+
+			got, err := CursorValue(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CursorValue() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !sameCursor(got, tt.want) {
+				t.Errorf("CursorValue() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeCursorNil(t *testing.T) {
+	if s := EncodeCursor(nil); s != "" {
+		t.Errorf("EncodeCursor(nil) = %q, want empty string", s)
+	}
+}
@@ -0,0 +1,66 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ETag returns a strong entity tag for the JSON representation of v.
+func ETag(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(b)
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// ETagMatches reports whether an If-Match header value lists etag. As
+// required for If-Match, weak tags never match.
+func ETagMatches(header, etag string) bool {
+	return matchETag(header, etag, false)
+}
+
+// WriteJSONETag is WriteJSON for reads: it tags the response with the ETag
+// of v and answers 304 Not Modified when the client already has it.
+func WriteJSONETag(w http.ResponseWriter, r *http.Request, status int, v any) error {
+	etag := ETag(v)
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+
+	if etag != "" && matchETag(r.Header.Get("If-None-Match"), etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	return WriteJSON(w, status, v)
+}
+
+func matchETag(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if tag == "*" {
+			return true
+		}
+
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+
+		if tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
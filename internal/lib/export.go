@@ -0,0 +1,152 @@
+package lib
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/types"
+)
+
+const (
+	ExportJSON   = "json"
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+)
+
+var exportContentTypes = map[string]string{
+	ExportJSON:   "application/json",
+	ExportCSV:    "text/csv; charset=utf-8",
+	ExportNDJSON: "application/x-ndjson",
+}
+
+// exportColumns is the CSV header of an export. POST /songs/import reads
+// all of them but id, so an export can be imported again.
+var exportColumns = []string{"id", "song", "group", "releaseDate", "text", "link", "albumId", "trackNumber"}
+
+// SongWriter streams songs to the response in one of the export formats.
+// Nothing, not even a header, is written before the first song, so an error
+// that happens earlier can still be answered with a regular error response.
+type SongWriter struct {
+	w      http.ResponseWriter
+	format string
+	json   *json.Encoder
+	csv    *csv.Writer
+	n      int
+}
+
+// NewSongWriter returns a SongWriter for format, JSON by default.
+func NewSongWriter(w http.ResponseWriter, format string) (*SongWriter, error) {
+	if format == "" {
+		format = ExportJSON
+	}
+
+	if _, ok := exportContentTypes[format]; !ok {
+		return nil, errs.InvalidExportFormat()
+	}
+
+	return &SongWriter{
+		w:      w,
+		format: format,
+		json:   json.NewEncoder(w),
+		csv:    csv.NewWriter(w),
+	}, nil
+}
+
+// Started reports whether any part of the body has been written.
+func (sw *SongWriter) Started() bool {
+	return sw.n > 0
+}
+
+func (sw *SongWriter) Write(song *types.Song) error {
+	if err := sw.begin(); err != nil {
+		return err
+	}
+
+	var err error
+
+	switch sw.format {
+	case ExportJSON:
+		if sw.n > 0 {
+			if _, err := io.WriteString(sw.w, ","); err != nil {
+				return err
+			}
+		}
+		err = sw.json.Encode(song)
+	case ExportNDJSON:
+		err = sw.json.Encode(song)
+	case ExportCSV:
+		err = sw.csv.Write(csvRecord(song))
+	}
+
+	sw.n++
+
+	return err
+}
+
+// Close finishes the body, which is a valid empty export if no song was
+// written.
+func (sw *SongWriter) Close() error {
+	if err := sw.begin(); err != nil {
+		return err
+	}
+
+	switch sw.format {
+	case ExportJSON:
+		_, err := io.WriteString(sw.w, "]\n")
+		return err
+	case ExportCSV:
+		sw.csv.Flush()
+		return sw.csv.Error()
+	}
+
+	return nil
+}
+
+// begin writes the headers and what precedes the first song.
+func (sw *SongWriter) begin() error {
+	if sw.n > 0 {
+		return nil
+	}
+
+	sw.w.Header().Set("Content-Type", exportContentTypes[sw.format])
+	sw.w.Header().Set("Content-Disposition", `attachment; filename="songs.`+sw.format+`"`)
+
+	switch sw.format {
+	case ExportJSON:
+		_, err := io.WriteString(sw.w, "[")
+		return err
+	case ExportCSV:
+		return sw.csv.Write(exportColumns)
+	}
+
+	return nil
+}
+
+func csvRecord(song *types.Song) []string {
+	optional := func(v *int) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.Itoa(*v)
+	}
+
+	var releaseDate string
+	if song.ReleaseDate != nil {
+		releaseDate = song.ReleaseDate.Format(Layout)
+	}
+
+	return []string{
+		strconv.Itoa(song.ID),
+		song.Song,
+		song.Group,
+		releaseDate,
+		song.Text,
+		song.Link,
+		optional(song.AlbumID),
+		optional(song.TrackNumber),
+	}
+}
@@ -0,0 +1,194 @@
+package lib
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/types"
+)
+
+const (
+	// MaxImportSize limits the size of an uploaded import file in bytes.
+	MaxImportSize = 32 << 20
+	// MaxImportRows limits the number of songs in one import.
+	MaxImportRows = 10000
+
+	maxImportLine = 1 << 20
+)
+
+// ImportRows parses the body of r as CSV with a header row or as JSON Lines,
+// depending on its Content-Type. Rows that can't be parsed are returned
+// with Err set so they can be reported alongside the others; an error is
+// only returned when the file as a whole is unusable.
+func ImportRows(w http.ResponseWriter, r *http.Request) ([]types.ImportRow, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, errs.UnsupportedImportType()
+	}
+
+	body := http.MaxBytesReader(w, r.Body, MaxImportSize)
+	defer body.Close()
+
+	var rows []types.ImportRow
+
+	switch mediaType {
+	case "text/csv":
+		rows, err = csvRows(body)
+	case "application/x-ndjson", "application/jsonl":
+		rows, err = jsonLinesRows(body)
+	default:
+		return nil, errs.UnsupportedImportType()
+	}
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return nil, errs.ImportTooLarge(tooLarge.Limit)
+	case err != nil:
+		return nil, err
+	case len(rows) == 0:
+		return nil, errs.EmptyImport()
+	}
+
+	return rows, nil
+}
+
+func csvRows(body io.Reader) ([]types.ImportRow, error) {
+	cr := csv.NewReader(body)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Spreadsheets often prepend a byte order mark to the first column.
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, required := range []string{"song", "group"} {
+		if _, ok := columns[required]; !ok {
+			return nil, errs.MissingImportColumn(required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[strings.ToLower(name)]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	// number reads an optional integer column, nil when it's empty.
+	number := func(record []string, name string, invalid error) (*int, error) {
+		v := field(record, name)
+		if v == "" {
+			return nil, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, invalid
+		}
+		return &n, nil
+	}
+
+	var rows []types.ImportRow
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, csvError(err)
+		}
+
+		line, _ := cr.FieldPos(0)
+		row := types.ImportRow{Line: line}
+
+		if err != nil {
+			row.Err = fmt.Errorf("expected %d fields, got %d", len(header), len(record))
+		} else {
+			row.Song = field(record, "song")
+			row.Group = field(record, "group")
+			row.ReleaseDate = field(record, "releaseDate")
+			row.Text = field(record, "text")
+			row.Link = field(record, "link")
+			row.AlbumID, row.Err = number(record, "albumId", errs.InvalidAlbumID())
+			if row.Err == nil {
+				row.TrackNumber, row.Err = number(record, "trackNumber", errs.InvalidTrackNumber())
+			}
+		}
+
+		if len(rows) == MaxImportRows {
+			return nil, errs.TooManyImportRows(MaxImportRows)
+		}
+		rows = append(rows, row)
+	}
+}
+
+// csvError reports a malformed CSV file. Quoting errors leave the reader
+// out of step with the lines, so the rest of the file can't be trusted.
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return errs.InvalidImport(parseErr.StartLine, parseErr.Err)
+	}
+	return err
+}
+
+func jsonLinesRows(body io.Reader) ([]types.ImportRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxImportLine)
+
+	var (
+		rows []types.ImportRow
+		line int
+	)
+
+	for scanner.Scan() {
+		line++
+
+		data := strings.TrimSpace(scanner.Text())
+		if data == "" {
+			continue
+		}
+
+		row := types.ImportRow{}
+		if err := json.Unmarshal([]byte(data), &row); err != nil {
+			row = types.ImportRow{Err: errors.New("invalid JSON")}
+		}
+		row.Line = line
+
+		if len(rows) == MaxImportRows {
+			return nil, errs.TooManyImportRows(MaxImportRows)
+		}
+		rows = append(rows, row)
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, errs.InvalidImport(line+1, err)
+		}
+		return nil, err
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,149 @@
+package lib
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/types"
+)
+
+// rowError drops the parse error of row so rows compare with ==, keeping
+// whether there was one.
+func rowError(row types.ImportRow) (types.ImportRow, bool) {
+	failed := row.Err != nil
+	row.Err = nil
+	return row, failed
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestImportRows(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        []types.ImportRow
+		wantFailed  []bool
+		wantErr     error
+	}{
+		{
+			name:        "csv",
+			contentType: "text/csv",
+			body: "song,group,releaseDate,text,link\n" +
+				"Uprising,Muse,07.09.2009,,https://example.com/uprising\n" +
+				"\"Creep\", Radiohead ,,\"When you were here before\",\n",
+			want: []types.ImportRow{
+				{Line: 2, Song: "Uprising", Group: "Muse", ReleaseDate: "07.09.2009", Link: "https://example.com/uprising"},
+				{Line: 3, Song: "Creep", Group: "Radiohead", Text: "When you were here before"},
+			},
+			wantFailed: []bool{false, false},
+		},
+		{
+			name:        "csv header in any order and case with a byte order mark",
+			contentType: "text/csv; charset=utf-8",
+			body:        "\ufeffGroup,SONG\nMuse,Hysteria\n",
+			want:        []types.ImportRow{{Line: 2, Song: "Hysteria", Group: "Muse"}},
+			wantFailed:  []bool{false},
+		},
+		{
+			name:        "csv row with a wrong field count",
+			contentType: "text/csv",
+			body:        "song,group\nHysteria\nUprising,Muse\n",
+			want: []types.ImportRow{
+				{Line: 2},
+				{Line: 3, Song: "Uprising", Group: "Muse"},
+			},
+			wantFailed: []bool{true, false},
+		},
+		{
+			name:        "csv with album columns",
+			contentType: "text/csv",
+			body: "id,song,group,releaseDate,text,link,albumId,trackNumber\n" +
+				"1,Uprising,Muse,07.09.2009,,,2,1\n" +
+				"2,Creep,Radiohead,21.09.1992,,,,\n" +
+				"3,Hysteria,Muse,01.12.2003,,,two,\n" +
+				"4,Starlight,Muse,04.09.2006,,,2,last\n",
+			want: []types.ImportRow{
+				{Line: 2, Song: "Uprising", Group: "Muse", ReleaseDate: "07.09.2009", AlbumID: intPtr(2), TrackNumber: intPtr(1)},
+				{Line: 3, Song: "Creep", Group: "Radiohead", ReleaseDate: "21.09.1992"},
+				{Line: 4, Song: "Hysteria", Group: "Muse", ReleaseDate: "01.12.2003"},
+				{Line: 5, Song: "Starlight", Group: "Muse", ReleaseDate: "04.09.2006", AlbumID: intPtr(2)},
+			},
+			wantFailed: []bool{false, false, true, true},
+		},
+		{
+			name:        "csv without a group column",
+			contentType: "text/csv",
+			body:        "song,releaseDate\nHysteria,01.12.2003\n",
+			wantErr:     errs.MissingImportColumn("group"),
+		},
+		{
+			name:        "csv header only",
+			contentType: "text/csv",
+			body:        "song,group\n",
+			wantErr:     errs.EmptyImport(),
+		},
+		{
+			name:        "json lines",
+			contentType: "application/x-ndjson",
+			body: `{"song": "Uprising", "group": "Muse", "releaseDate": "07.09.2009", "albumId": 2, "trackNumber": 1}` + "\n" +
+				"\n" +
+				`{"song": "Creep", "group": "Radiohead", "text": "When you were here before"}` + "\n",
+			want: []types.ImportRow{
+				{Line: 1, Song: "Uprising", Group: "Muse", ReleaseDate: "07.09.2009", AlbumID: intPtr(2), TrackNumber: intPtr(1)},
+				{Line: 3, Song: "Creep", Group: "Radiohead", Text: "When you were here before"},
+			},
+			wantFailed: []bool{false, false},
+		},
+		{
+			name:        "json lines with an invalid line",
+			contentType: "application/jsonl",
+			body:        "{\"song\": \"Uprising\"\n" + `{"song": "Creep", "group": "Radiohead"}`,
+			want: []types.ImportRow{
+				{Line: 1},
+				{Line: 2, Song: "Creep", Group: "Radiohead"},
+			},
+			wantFailed: []bool{true, false},
+		},
+		{
+			name:        "empty json lines",
+			contentType: "application/x-ndjson",
+			body:        "\n\n",
+			wantErr:     errs.EmptyImport(),
+		},
+		{
+			name:        "unsupported type",
+			contentType: "application/json",
+			body:        `[{"song": "Creep", "group": "Radiohead"}]`,
+			wantErr:     errs.UnsupportedImportType(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/songs/import", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+
+			rows, err := ImportRows(httptest.NewRecorder(), r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ImportRows() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if len(rows) != len(tt.want) {
+				t.Fatalf("ImportRows() = %+v, want %+v", rows, tt.want)
+			}
+			for i, want := range tt.want {
+				got, failed := rowError(rows[i])
+				if !reflect.DeepEqual(got, want) || failed != tt.wantFailed[i] {
+					t.Errorf("row %d = %+v (failed %v), want %+v (failed %v)", i, got, failed, want, tt.wantFailed[i])
+				}
+			}
+		})
+	}
+}
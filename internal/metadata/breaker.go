@@ -0,0 +1,112 @@
+package metadata
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Breaker states.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// ErrCircuitOpen is returned without calling the upstream while the breaker
+// is open.
+var ErrCircuitOpen = errors.New("details API unavailable: circuit breaker is open")
+
+// Breaker stops calls to an upstream that keeps failing. It opens after
+// threshold failures in a row and, once cooldown has passed, lets a single
+// call through: the breaker closes if it succeeds and opens again if not.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	// probing is set while the half-open breaker waits for its trial call.
+	probing bool
+}
+
+// NewBreaker returns a closed breaker. A threshold below 1 never opens.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     BreakerClosed,
+	}
+}
+
+// Allow reports whether a call may go through. Every allowed call must be
+// followed by Success, Failure or Cancel.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = BreakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+
+	if b.state == BreakerHalfOpen || (b.threshold > 0 && b.failures >= b.threshold) {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// Cancel gives back a call that ended without telling anything about the
+// upstream, such as one whose context was canceled.
+func (b *Breaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// State returns the state of the breaker, the failures in a row and, while
+// open, when the next trial call is allowed.
+func (b *Breaker) State() (state string, failures int, retryAt *time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		t := b.openedAt.Add(b.cooldown)
+		retryAt = &t
+	}
+
+	return b.state, b.failures, retryAt
+}
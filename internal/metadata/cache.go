@@ -0,0 +1,97 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/erknas/song-library/internal/lib"
+	"github.com/erknas/song-library/internal/types"
+)
+
+// DetailsCache keeps details lookups by DetailsKey. Get returns nil for a
+// key that isn't cached or has expired.
+type DetailsCache interface {
+	Get(context.Context, string) (*types.CachedDetails, error)
+	Set(context.Context, *types.CachedDetails) error
+	Delete(context.Context, string) (bool, error)
+	Clear(context.Context) (int, error)
+}
+
+// DetailsKey is the cache key of a song, the same for any case and
+// whitespace of its name and group.
+func DetailsKey(song, group string) string {
+	return detailsKey(song, group)
+}
+
+// CachedProvider remembers the details found by a provider for ttl and the
+// songs it had no details for for negativeTTL. Failed lookups aren't cached.
+type CachedProvider struct {
+	provider    DetailsProvider
+	cache       DetailsCache
+	ttl         time.Duration
+	negativeTTL time.Duration
+}
+
+func NewCachedProvider(provider DetailsProvider, cache DetailsCache, ttl, negativeTTL time.Duration) *CachedProvider {
+	return &CachedProvider{
+		provider:    provider,
+		cache:       cache,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+// SongDetails asks the provider on a cache miss. A cache that fails to read
+// or write only costs provider calls, so its errors are not returned.
+func (p *CachedProvider) SongDetails(ctx context.Context, req *types.SongRequest) (*types.Song, error) {
+	key := DetailsKey(req.Song, req.Group)
+
+	if cached, err := p.cache.Get(ctx, key); err == nil && cached != nil {
+		if cached.Details == nil {
+			return nil, ErrNotFound
+		}
+		if song, err := songDetails(cached.Details); err == nil {
+			return song, nil
+		}
+	}
+
+	song, err := p.provider.SongDetails(ctx, req)
+
+	switch {
+	case err == nil && p.ttl > 0:
+		p.cache.Set(ctx, &types.CachedDetails{
+			Key: key,
+			Details: &types.Details{
+				ReleaseDate: song.ReleaseDate.Format(lib.Layout),
+				Text:        song.Text,
+				Link:        song.Link,
+			},
+			ExpiresAt: time.Now().Add(p.ttl),
+		})
+	case errors.Is(err, ErrNotFound) && p.negativeTTL > 0:
+		p.cache.Set(ctx, &types.CachedDetails{
+			Key:       key,
+			ExpiresAt: time.Now().Add(p.negativeTTL),
+		})
+	}
+
+	return song, err
+}
+
+func (p *CachedProvider) Health() []types.ProviderHealth {
+	return p.provider.Health()
+}
+
+func songDetails(details *types.Details) (*types.Song, error) {
+	releaseDate, err := time.Parse(lib.Layout, details.ReleaseDate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Song{
+		ReleaseDate: &releaseDate,
+		Text:        details.Text,
+		Link:        details.Link,
+	}, nil
+}
@@ -0,0 +1,147 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/erknas/song-library/internal/types"
+)
+
+func TestLRUCacheEviction(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		// ops are run in order: "set:k" caches k, "get:k" reads it.
+		ops  []string
+		want []string
+	}{
+		{"within size", 3, []string{"set:a", "set:b", "set:c"}, []string{"a", "b", "c"}},
+		{"oldest evicted", 2, []string{"set:a", "set:b", "set:c"}, []string{"b", "c"}},
+		{"read keeps an entry", 2, []string{"set:a", "set:b", "get:a", "set:c"}, []string{"a", "c"}},
+		{"overwrite keeps an entry", 2, []string{"set:a", "set:b", "set:a", "set:c"}, []string{"a", "c"}},
+		{"overwrite doesn't evict", 2, []string{"set:a", "set:b", "set:b"}, []string{"a", "b"}},
+		{"size one", 1, []string{"set:a", "set:b"}, []string{"b"}},
+		{"no caching", 0, []string{"set:a", "set:b"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := NewLRUCache(tt.size)
+			expiresAt := time.Now().Add(time.Hour)
+
+			for _, op := range tt.ops {
+				switch key := op[4:]; op[:4] {
+				case "set:":
+					c.Set(ctx, &types.CachedDetails{Key: key, ExpiresAt: expiresAt})
+				case "get:":
+					c.Get(ctx, key)
+				}
+			}
+
+			var got []string
+			for _, key := range []string{"a", "b", "c"} {
+				if cached, _ := c.Get(ctx, key); cached != nil {
+					got = append(got, key)
+				}
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("cached keys = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLRUCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRUCache(2)
+
+	c.Set(ctx, &types.CachedDetails{Key: "expired", ExpiresAt: time.Now().Add(-time.Second)})
+	c.Set(ctx, &types.CachedDetails{Key: "fresh", ExpiresAt: time.Now().Add(time.Hour)})
+
+	if cached, _ := c.Get(ctx, "expired"); cached != nil {
+		t.Errorf("Get(expired) = %+v, want nil", cached)
+	}
+	if cached, _ := c.Get(ctx, "fresh"); cached == nil {
+		t.Error("Get(fresh) = nil, want the entry")
+	}
+
+	// The expired entry is dropped on read, so both of these fit.
+	c.Set(ctx, &types.CachedDetails{Key: "new", ExpiresAt: time.Now().Add(time.Hour)})
+	if cached, _ := c.Get(ctx, "fresh"); cached == nil {
+		t.Error("Get(fresh) after Set(new) = nil, want the entry")
+	}
+}
+
+func TestCachedProvider(t *testing.T) {
+	errDown := errors.New("api is down")
+
+	tests := []struct {
+		name        string
+		provider    *stubProvider
+		ttl         time.Duration
+		negativeTTL time.Duration
+		wantLink    string
+		wantErr     error
+		wantCalls   int
+	}{
+		{"found cached", found("cached"), time.Hour, time.Hour, "cached", nil, 1},
+		{"found not cached without ttl", found("uncached"), 0, time.Hour, "uncached", nil, 2},
+		{"not found cached", notFound(), time.Hour, time.Hour, "", ErrNotFound, 1},
+		{"not found not cached without negative ttl", notFound(), time.Hour, 0, "", ErrNotFound, 2},
+		{"errors not cached", &stubProvider{err: errDown}, time.Hour, time.Hour, "", errDown, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewCachedProvider(tt.provider, NewLRUCache(10), tt.ttl, tt.negativeTTL)
+
+			// The second lookup differs only in case and whitespace, so it
+			// has the same key.
+			for _, req := range []types.SongRequest{
+				{Song: "Uprising", Group: "Muse"},
+				{Song: " uprising ", Group: "MUSE"},
+			} {
+				song, err := p.SongDetails(context.Background(), &req)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SongDetails(%q) error = %v, want %v", req.Song, err, tt.wantErr)
+				}
+				if err == nil && song.Link != tt.wantLink {
+					t.Errorf("SongDetails(%q) link = %q, want %q", req.Song, song.Link, tt.wantLink)
+				}
+			}
+
+			if tt.provider.calls != tt.wantCalls {
+				t.Errorf("provider calls = %d, want %d", tt.provider.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCachedProviderExpiry(t *testing.T) {
+	provider := found("link")
+	cache := NewLRUCache(10)
+	p := NewCachedProvider(provider, cache, time.Hour, time.Hour)
+	req := &types.SongRequest{Song: "Uprising", Group: "Muse"}
+
+	if _, err := p.SongDetails(context.Background(), req); err != nil {
+		t.Fatalf("SongDetails() error = %v", err)
+	}
+
+	cached, _ := cache.Get(context.Background(), DetailsKey(req.Song, req.Group))
+	if cached == nil {
+		t.Fatal("details not cached")
+	}
+	cached.ExpiresAt = time.Now().Add(-time.Second)
+
+	if _, err := p.SongDetails(context.Background(), req); err != nil {
+		t.Fatalf("SongDetails() after expiry error = %v", err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("provider calls = %d, want 2", provider.calls)
+	}
+}
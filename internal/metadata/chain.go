@@ -0,0 +1,53 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+
+	"github.com/erknas/song-library/internal/types"
+)
+
+// Chain asks its providers in order and returns the first details found.
+// A provider that fails or has no details falls back to the next one.
+type Chain struct {
+	providers []DetailsProvider
+}
+
+func NewChain(providers ...DetailsProvider) *Chain {
+	return &Chain{providers: providers}
+}
+
+// SongDetails returns ErrNotFound if no provider has the details, or else
+// the errors of the providers that failed.
+func (c *Chain) SongDetails(ctx context.Context, req *types.SongRequest) (*types.Song, error) {
+	var errs []error
+
+	for _, p := range c.providers {
+		details, err := p.SongDetails(ctx, req)
+		if err == nil {
+			return details, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if !errors.Is(err, ErrNotFound) {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+func (c *Chain) Health() []types.ProviderHealth {
+	var health []types.ProviderHealth
+	for _, p := range c.providers {
+		health = append(health, p.Health()...)
+	}
+	return health
+}
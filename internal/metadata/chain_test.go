@@ -0,0 +1,114 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/erknas/song-library/internal/types"
+)
+
+// stubProvider answers every lookup with its details or err and counts the
+// lookups.
+type stubProvider struct {
+	details *types.Song
+	err     error
+	calls   int
+}
+
+func (p *stubProvider) SongDetails(ctx context.Context, req *types.SongRequest) (*types.Song, error) {
+	p.calls++
+	return p.details, p.err
+}
+
+func (p *stubProvider) Health() []types.ProviderHealth {
+	return nil
+}
+
+func found(link string) *stubProvider {
+	releaseDate := time.Date(2009, time.September, 7, 0, 0, 0, 0, time.UTC)
+	return &stubProvider{details: &types.Song{ReleaseDate: &releaseDate, Link: link}}
+}
+
+func notFound() *stubProvider {
+	return &stubProvider{err: ErrNotFound}
+}
+
+func TestChain(t *testing.T) {
+	var (
+		errDown    = errors.New("api is down")
+		errTimeout = errors.New("api timed out")
+	)
+
+	tests := []struct {
+		name         string
+		providers    []*stubProvider
+		wantLink     string
+		wantNotFound bool
+		wantErrs     []error
+		wantCalls    []int
+	}{
+		{"first found", []*stubProvider{found("first"), found("second")}, "first", false, nil, []int{1, 0}},
+		{"falls back after not found", []*stubProvider{notFound(), found("second")}, "second", false, nil, []int{1, 1}},
+		{"falls back after an error", []*stubProvider{{err: errDown}, notFound(), found("third")}, "third", false, nil, []int{1, 1, 1}},
+		{"all not found", []*stubProvider{notFound(), notFound()}, "", true, nil, []int{1, 1}},
+		{"no providers", nil, "", true, nil, nil},
+		// A failed provider might have had the details, so the lookup
+		// isn't reported as not found.
+		{"errors joined", []*stubProvider{{err: errDown}, notFound(), {err: errTimeout}}, "", false, []error{errDown, errTimeout}, []int{1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers := make([]DetailsProvider, len(tt.providers))
+			for i, p := range tt.providers {
+				providers[i] = p
+			}
+
+			details, err := NewChain(providers...).SongDetails(context.Background(), &types.SongRequest{Song: "Uprising", Group: "Muse"})
+
+			if tt.wantLink != "" {
+				if err != nil {
+					t.Fatalf("SongDetails() error = %v", err)
+				}
+				if details.Link != tt.wantLink {
+					t.Errorf("SongDetails() link = %q, want %q", details.Link, tt.wantLink)
+				}
+			} else {
+				if err == nil {
+					t.Fatalf("SongDetails() = %+v, want error", details)
+				}
+				if errors.Is(err, ErrNotFound) != tt.wantNotFound {
+					t.Errorf("SongDetails() error = %v, want not found %v", err, tt.wantNotFound)
+				}
+				for _, want := range tt.wantErrs {
+					if !errors.Is(err, want) {
+						t.Errorf("SongDetails() error = %v, want it to match %v", err, want)
+					}
+				}
+			}
+
+			for i, p := range tt.providers {
+				if p.calls != tt.wantCalls[i] {
+					t.Errorf("provider %d calls = %d, want %d", i, p.calls, tt.wantCalls[i])
+				}
+			}
+		})
+	}
+}
+
+func TestChainContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	next := found("second")
+	chain := NewChain(&stubProvider{err: ctx.Err()}, next)
+
+	if _, err := chain.SongDetails(ctx, &types.SongRequest{Song: "Uprising", Group: "Muse"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SongDetails() error = %v, want context.Canceled", err)
+	}
+	if next.calls != 0 {
+		t.Errorf("next provider calls = %d, want 0", next.calls)
+	}
+}
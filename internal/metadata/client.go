@@ -0,0 +1,173 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/erknas/song-library/internal/config"
+)
+
+// maxDrain is how much of a discarded response body is read so that its
+// connection can be reused.
+const maxDrain = 64 << 10
+
+// Client calls the details API. It keeps connections alive between calls,
+// retries 5xx and 429 responses and transport errors with jittered
+// exponential backoff, honoring Retry-After, and goes through a Breaker.
+type Client struct {
+	client        *http.Client
+	breaker       *Breaker
+	retries       int
+	retryDelay    time.Duration
+	maxRetryDelay time.Duration
+	// wait pauses between attempts.
+	wait func(ctx context.Context, d time.Duration) error
+}
+
+func NewClient(cfg config.DetailsConfig) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 16
+
+	return &Client{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.DetailsTimeout,
+		},
+		breaker:       NewBreaker(cfg.DetailsBreakerThreshold, cfg.DetailsBreakerCooldown),
+		retries:       cfg.DetailsRetries,
+		retryDelay:    cfg.DetailsRetryDelay,
+		maxRetryDelay: cfg.DetailsMaxRetryDelay,
+		wait:          sleep,
+	}
+}
+
+func (c *Client) Breaker() *Breaker {
+	return c.breaker
+}
+
+// Get requests url until it gets a response that isn't worth retrying, and
+// returns it whatever its status code. The caller closes the body.
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	if !c.breaker.Allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := c.get(ctx, url)
+
+	switch {
+	case ctx.Err() != nil:
+		c.breaker.Cancel()
+	case err != nil:
+		c.breaker.Failure()
+	default:
+		c.breaker.Success()
+	}
+
+	return resp, err
+}
+
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.client.Do(req)
+		if err == nil && !retryable(resp.StatusCode) {
+			return resp, nil
+		}
+
+		var retryAfter time.Duration
+		if err == nil {
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			err = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrain))
+			resp.Body.Close()
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if attempt == c.retries {
+			return nil, err
+		}
+
+		delay := c.backoff(attempt)
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+
+		// Waiting longer than allowed, or past the deadline, can't help.
+		if delay > c.maxRetryDelay {
+			return nil, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, err
+		}
+
+		if err := c.wait(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff doubles retryDelay for every attempt, up to maxRetryDelay, and
+// picks a random delay between half of that and all of it.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.retryDelay
+	for range attempt {
+		if delay >= c.maxRetryDelay {
+			break
+		}
+		delay *= 2
+	}
+	delay = min(delay, c.maxRetryDelay)
+
+	if delay <= 0 {
+		return 0
+	}
+
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func retryable(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an
+// HTTP date. It returns 0 if there is none.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(secs)*time.Second, 0)
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+
+	return 0
+}
@@ -0,0 +1,350 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/erknas/song-library/internal/config"
+)
+
+// newTestClient returns a client with short delays so that retries don't
+// slow the tests down.
+func newTestClient(retries int) *Client {
+	return NewClient(config.DetailsConfig{
+		DetailsTimeout:          time.Second,
+		DetailsRetries:          retries,
+		DetailsRetryDelay:       time.Millisecond,
+		DetailsMaxRetryDelay:    10 * time.Millisecond,
+		DetailsBreakerThreshold: 0, // never opens
+	})
+}
+
+// statusServer answers the n-th request with statuses[n], repeating the last
+// one, and counts the requests.
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		w.WriteHeader(statuses[min(n, len(statuses)-1)])
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &calls
+}
+
+func TestClientGetRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		retries    int
+		statuses   []int
+		wantStatus int
+		wantErr    bool
+		wantCalls  int
+	}{
+		{"ok", 2, []int{200}, 200, false, 1},
+		{"retry on 500", 2, []int{500, 200}, 200, false, 2},
+		{"retry on 503 twice", 2, []int{503, 502, 200}, 200, false, 3},
+		{"retry on 429", 2, []int{429, 200}, 200, false, 2},
+		{"no retry on 404", 2, []int{404}, 404, false, 1},
+		{"no retry on 400", 2, []int{400, 200}, 400, false, 1},
+		{"give up after max attempts", 2, []int{500}, 0, true, 3},
+		{"no retries", 0, []int{500, 200}, 0, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := statusServer(t, tt.statuses...)
+			c := newTestClient(tt.retries)
+
+			resp, err := c.Get(context.Background(), srv.URL)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatalf("Get() status = %d, want error", resp.StatusCode)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != tt.wantStatus {
+					t.Errorf("Get() status = %d, want %d", resp.StatusCode, tt.wantStatus)
+				}
+			}
+
+			if got := int(calls.Load()); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// recordWaits makes c return from its waits between attempts right away
+// and returns the delays it was asked to wait.
+func recordWaits(c *Client) *[]time.Duration {
+	var waits []time.Duration
+	c.wait = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return &waits
+}
+
+func TestClientGetRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		wantErr    bool
+		wantCalls  int
+		wantWaits  []time.Duration
+	}{
+		{"honored", "1", false, 2, []time.Duration{time.Second}},
+		{"zero", "0", false, 2, nil},
+		{"beyond max retry delay", "5", true, 1, nil},
+		{"invalid falls back to backoff", "soon", false, 2, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					w.Header().Set("Retry-After", tt.retryAfter)
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			c := newTestClient(2)
+			c.maxRetryDelay = 2 * time.Second
+			waits := recordWaits(c)
+
+			resp, err := c.Get(context.Background(), srv.URL)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("Get() error = nil, want error")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+				resp.Body.Close()
+			}
+
+			if got := int(calls.Load()); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+
+			// Without a usable Retry-After the client backs off as usual.
+			if tt.wantWaits == nil && tt.wantCalls > 1 {
+				if len(*waits) != 1 || (*waits)[0] > c.retryDelay {
+					t.Errorf("waits = %v, want one backoff up to %v", *waits, c.retryDelay)
+				}
+				return
+			}
+			if !slices.Equal(*waits, tt.wantWaits) {
+				t.Errorf("waits = %v, want %v", *waits, tt.wantWaits)
+			}
+		})
+	}
+}
+
+func TestClientGetDeadline(t *testing.T) {
+	srv, calls := statusServer(t, http.StatusServiceUnavailable)
+
+	c := newTestClient(5)
+	c.retryDelay = time.Second
+	c.maxRetryDelay = time.Second
+
+	// The first backoff is at least half a second, past the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.Get(ctx, srv.URL); err == nil {
+		t.Fatal("Get() error = nil, want error")
+	}
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestClientBackoff(t *testing.T) {
+	c := &Client{
+		retryDelay:    100 * time.Millisecond,
+		maxRetryDelay: time.Second,
+	}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{2, 400 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second},
+		{10, time.Second},
+		{100, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.attempt), func(t *testing.T) {
+			for range 100 {
+				got := c.backoff(tt.attempt)
+				if got < tt.want/2 || got > tt.want {
+					t.Fatalf("backoff(%d) = %v, want between %v and %v", tt.attempt, got, tt.want/2, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{"missing", "", 0, 0},
+		{"seconds", "3", 3 * time.Second, 3 * time.Second},
+		{"negative", "-1", 0, 0},
+		{"invalid", "soon", 0, 0},
+		{"date", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 58 * time.Second, time.Minute},
+		{"past date", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseRetryAfter(tt.value)
+			if got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("parseRetryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestClientBreaker(t *testing.T) {
+	var (
+		calls   atomic.Int32
+		failing atomic.Bool
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	const cooldown = 50 * time.Millisecond
+
+	c := newTestClient(0)
+	c.breaker = NewBreaker(2, cooldown)
+
+	get := func() error {
+		resp, err := c.Get(context.Background(), srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	wantState := func(want string) {
+		t.Helper()
+		if got, _, _ := c.Breaker().State(); got != want {
+			t.Fatalf("state = %q, want %q", got, want)
+		}
+	}
+
+	failing.Store(true)
+
+	// closed -> open after threshold failures in a row.
+	for range 2 {
+		wantState(BreakerClosed)
+		if err := get(); err == nil {
+			t.Fatal("Get() error = nil, want error")
+		}
+	}
+	wantState(BreakerOpen)
+
+	if _, _, retryAt := c.Breaker().State(); retryAt == nil {
+		t.Error("open breaker has no retryAt")
+	}
+
+	// An open breaker doesn't call the upstream.
+	before := calls.Load()
+	if err := get(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Get() error = %v, want ErrCircuitOpen", err)
+	}
+	if calls.Load() != before {
+		t.Error("open breaker called the upstream")
+	}
+
+	// open -> half-open -> open when the trial call fails.
+	time.Sleep(cooldown)
+	if err := get(); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Get() error = %v, want upstream error", err)
+	}
+	wantState(BreakerOpen)
+
+	// open -> half-open -> closed when the trial call succeeds.
+	failing.Store(false)
+	time.Sleep(cooldown)
+	if err := get(); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	wantState(BreakerClosed)
+
+	if _, failures, _ := c.Breaker().State(); failures != 0 {
+		t.Errorf("failures = %d, want 0", failures)
+	}
+}
+
+func TestBreakerHalfOpenSingleProbe(t *testing.T) {
+	b := NewBreaker(1, 0)
+
+	b.Failure()
+	if state, _, _ := b.State(); state != BreakerOpen {
+		t.Fatalf("state = %q, want %q", state, BreakerOpen)
+	}
+
+	if !b.Allow() {
+		t.Fatal("Allow() = false, want the trial call")
+	}
+	if state, _, _ := b.State(); state != BreakerHalfOpen {
+		t.Fatalf("state = %q, want %q", state, BreakerHalfOpen)
+	}
+	if b.Allow() {
+		t.Fatal("Allow() = true while the trial call is running")
+	}
+
+	// A canceled trial call lets the next one through.
+	b.Cancel()
+	if !b.Allow() {
+		t.Fatal("Allow() = false after the trial call was canceled")
+	}
+
+	b.Success()
+	if state, _, _ := b.State(); state != BreakerClosed {
+		t.Fatalf("state = %q, want %q", state, BreakerClosed)
+	}
+}
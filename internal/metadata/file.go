@@ -0,0 +1,80 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/erknas/song-library/internal/config"
+	"github.com/erknas/song-library/internal/lib"
+	"github.com/erknas/song-library/internal/types"
+)
+
+// fileRecord is an entry of the details file.
+type fileRecord struct {
+	Song        string `json:"song"`
+	Group       string `json:"group"`
+	ReleaseDate string `json:"releaseDate"`
+	Text        string `json:"text"`
+	Link        string `json:"link"`
+}
+
+// FileProvider serves song details from a JSON file holding an array of
+// {song, group, releaseDate, text, link} objects. The file is read once.
+type FileProvider struct {
+	details map[string]*types.Song
+}
+
+func NewFileProvider(path string) (*FileProvider, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read details file: %w", err)
+	}
+
+	var records []fileRecord
+
+	if err := json.Unmarshal(b, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode details file: %w", err)
+	}
+
+	p := &FileProvider{details: make(map[string]*types.Song, len(records))}
+
+	for i, rec := range records {
+		if rec.Song == "" || rec.Group == "" {
+			return nil, fmt.Errorf("details file entry %d: song and group are required", i)
+		}
+
+		releaseDate, err := time.Parse(lib.Layout, rec.ReleaseDate)
+		if err != nil {
+			return nil, fmt.Errorf("details file entry %d: invalid release date %q", i, rec.ReleaseDate)
+		}
+
+		p.details[detailsKey(rec.Song, rec.Group)] = &types.Song{
+			ReleaseDate: &releaseDate,
+			Text:        rec.Text,
+			Link:        rec.Link,
+		}
+	}
+
+	return p, nil
+}
+
+func (p *FileProvider) SongDetails(ctx context.Context, req *types.SongRequest) (*types.Song, error) {
+	details, ok := p.details[detailsKey(req.Song, req.Group)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	// Callers own the result.
+	song := *details
+	releaseDate := *details.ReleaseDate
+	song.ReleaseDate = &releaseDate
+
+	return &song, nil
+}
+
+func (p *FileProvider) Health() []types.ProviderHealth {
+	return []types.ProviderHealth{{Provider: config.DetailsFile, Up: true}}
+}
@@ -0,0 +1,119 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/erknas/song-library/internal/types"
+)
+
+// writeDetailsFile writes data to a details file in a temporary directory
+// and returns its path.
+func writeDetailsFile(t *testing.T, data string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "details.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	return path
+}
+
+func TestFileProvider(t *testing.T) {
+	path := writeDetailsFile(t, `[
+		{"song": "Uprising", "group": "Muse", "releaseDate": "07.09.2009", "text": "Paranoia is in bloom", "link": "https://example.com/uprising"},
+		{"song": "Karma Police", "group": "Radiohead", "releaseDate": "25.08.1997"}
+	]`)
+
+	p, err := NewFileProvider(path)
+	if err != nil {
+		t.Fatalf("NewFileProvider() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		req      types.SongRequest
+		wantDate time.Time
+		wantText string
+		wantErr  error
+	}{
+		{"exact", types.SongRequest{Song: "Uprising", Group: "Muse"}, time.Date(2009, time.September, 7, 0, 0, 0, 0, time.UTC), "Paranoia is in bloom", nil},
+		{"case and spaces", types.SongRequest{Song: " karma  POLICE", Group: "radiohead "}, time.Date(1997, time.August, 25, 0, 0, 0, 0, time.UTC), "", nil},
+		{"unknown song", types.SongRequest{Song: "Hysteria", Group: "Muse"}, time.Time{}, "", ErrNotFound},
+		{"other group", types.SongRequest{Song: "Uprising", Group: "Radiohead"}, time.Time{}, "", ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details, err := p.SongDetails(context.Background(), &tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SongDetails() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if !details.ReleaseDate.Equal(tt.wantDate) || details.Text != tt.wantText {
+				t.Errorf("SongDetails() = %v %q, want %v %q", details.ReleaseDate, details.Text, tt.wantDate, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestFileProviderReturnsCopies(t *testing.T) {
+	path := writeDetailsFile(t, `[{"song": "Uprising", "group": "Muse", "releaseDate": "07.09.2009"}]`)
+
+	p, err := NewFileProvider(path)
+	if err != nil {
+		t.Fatalf("NewFileProvider() error = %v", err)
+	}
+
+	req := &types.SongRequest{Song: "Uprising", Group: "Muse"}
+
+	first, err := p.SongDetails(context.Background(), req)
+	if err != nil {
+		t.Fatalf("SongDetails() error = %v", err)
+	}
+	*first.ReleaseDate = first.ReleaseDate.AddDate(1, 0, 0)
+	first.Text = "changed"
+
+	second, err := p.SongDetails(context.Background(), req)
+	if err != nil {
+		t.Fatalf("SongDetails() error = %v", err)
+	}
+	if second.ReleaseDate.Year() != 2009 || second.Text != "" {
+		t.Errorf("SongDetails() = %v %q after the caller changed an earlier result", second.ReleaseDate, second.Text)
+	}
+}
+
+func TestNewFileProviderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not JSON", `song,group`},
+		{"not an array", `{"song": "Uprising", "group": "Muse", "releaseDate": "07.09.2009"}`},
+		{"missing song", `[{"group": "Muse", "releaseDate": "07.09.2009"}]`},
+		{"missing group", `[{"song": "Uprising", "releaseDate": "07.09.2009"}]`},
+		{"invalid release date", `[{"song": "Uprising", "group": "Muse", "releaseDate": "2009-09-07"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFileProvider(writeDetailsFile(t, tt.data)); err == nil {
+				t.Fatal("NewFileProvider() error = nil, want error")
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := NewFileProvider(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+			t.Fatal("NewFileProvider() error = nil, want error")
+		}
+	})
+}
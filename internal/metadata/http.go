@@ -0,0 +1,77 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/erknas/song-library/internal/config"
+	"github.com/erknas/song-library/internal/lib"
+	"github.com/erknas/song-library/internal/types"
+)
+
+// HTTPProvider gets song details from the third-party API, which answers
+// GET url?group=...&song=... with types.Details.
+type HTTPProvider struct {
+	url    string
+	client *Client
+}
+
+func NewHTTPProvider(url string, client *Client) *HTTPProvider {
+	return &HTTPProvider{
+		url:    url,
+		client: client,
+	}
+}
+
+func (p *HTTPProvider) SongDetails(ctx context.Context, req *types.SongRequest) (*types.Song, error) {
+	u, err := lib.ParseURL(p.url, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	details := new(types.Details)
+
+	if err := json.NewDecoder(resp.Body).Decode(details); err != nil {
+		return nil, fmt.Errorf("failed to decode details: %w", err)
+	}
+
+	releaseDate, err := time.Parse(lib.Layout, details.ReleaseDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse release date: %w", err)
+	}
+
+	return &types.Song{
+		ReleaseDate: &releaseDate,
+		Text:        details.Text,
+		Link:        details.Link,
+	}, nil
+}
+
+func (p *HTTPProvider) Health() []types.ProviderHealth {
+	state, failures, retryAt := p.client.Breaker().State()
+
+	return []types.ProviderHealth{{
+		Provider: config.DetailsHTTP,
+		Up:       state != BreakerOpen,
+		Breaker:  state,
+		Failures: failures,
+		RetryAt:  retryAt,
+	}}
+}
@@ -0,0 +1,101 @@
+package metadata
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/erknas/song-library/internal/types"
+)
+
+// LRUCache is an in-process DetailsCache holding at most size entries. The
+// least recently used entry is evicted to make room for a new one.
+type LRUCache struct {
+	size int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *LRUCache) Get(ctx context.Context, key string) (*types.CachedDetails, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, nil
+	}
+
+	cached := el.Value.(*types.CachedDetails)
+	if !time.Now().Before(cached.ExpiresAt) {
+		c.remove(el)
+		return nil, nil
+	}
+
+	c.order.MoveToFront(el)
+
+	return cached, nil
+}
+
+func (c *LRUCache) Set(ctx context.Context, cached *types.CachedDetails) error {
+	if c.size <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[cached.Key]; ok {
+		el.Value = cached
+		c.order.MoveToFront(el)
+		return nil
+	}
+
+	for c.order.Len() >= c.size {
+		c.remove(c.order.Back())
+	}
+
+	c.entries[cached.Key] = c.order.PushFront(cached)
+
+	return nil
+}
+
+func (c *LRUCache) Delete(ctx context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return false, nil
+	}
+
+	c.remove(el)
+
+	return true, nil
+}
+
+func (c *LRUCache) Clear(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := len(c.entries)
+	c.order.Init()
+	clear(c.entries)
+
+	return n, nil
+}
+
+func (c *LRUCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*types.CachedDetails).Key)
+}
@@ -0,0 +1,66 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/erknas/song-library/internal/config"
+	"github.com/erknas/song-library/internal/types"
+)
+
+// ErrNotFound is returned by a provider that has no details for a song.
+var ErrNotFound = errors.New("song details not found")
+
+// DetailsProvider looks up the release date, text and link of a song. The
+// returned song has only those fields set. Health describes the provider,
+// or each provider it is made of.
+type DetailsProvider interface {
+	SongDetails(context.Context, *types.SongRequest) (*types.Song, error)
+	Health() []types.ProviderHealth
+}
+
+// New builds the providers listed in cfg.DetailsProviders, in order. More
+// than one are chained, each falling back to the next. Lookups in the API
+// go through cache unless it is nil.
+func New(cfg config.DetailsConfig, cache DetailsCache) (DetailsProvider, error) {
+	if len(cfg.DetailsProviders) == 0 {
+		return nil, errors.New("no details providers")
+	}
+
+	providers := make([]DetailsProvider, 0, len(cfg.DetailsProviders))
+
+	for _, name := range cfg.DetailsProviders {
+		switch strings.TrimSpace(name) {
+		case config.DetailsHTTP:
+			var provider DetailsProvider = NewHTTPProvider(cfg.ThirdPartyAPIURL, NewClient(cfg))
+			if cache != nil {
+				provider = NewCachedProvider(provider, cache, cfg.DetailsCacheTTL, cfg.DetailsCacheNegativeTTL)
+			}
+			providers = append(providers, provider)
+		case config.DetailsFile:
+			provider, err := NewFileProvider(cfg.DetailsFile)
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, provider)
+		default:
+			return nil, fmt.Errorf("unknown details provider %q", name)
+		}
+	}
+
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+
+	return NewChain(providers...), nil
+}
+
+// detailsKey matches songs regardless of case and whitespace.
+func detailsKey(song, group string) string {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.Join(strings.Fields(s), " "))
+	}
+	return normalize(group) + "\n" + normalize(song)
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/lib"
+	"github.com/erknas/song-library/internal/logger/sl"
+	"github.com/erknas/song-library/internal/storage"
+	"github.com/erknas/song-library/internal/types"
+)
+
+const (
+	getAlbumsFn      = "GetAlbums"
+	getAlbumFn       = "GetAlbum"
+	addAlbumFn       = "AddAlbum"
+	updateAlbumFn    = "UpdateAlbum"
+	deleteAlbumFn    = "DeleteAlbum"
+	getAlbumTracksFn = "GetAlbumTracks"
+)
+
+func (s *Service) GetAlbums(ctx context.Context, pag types.Pagination) ([]*types.Album, error) {
+	log := s.log.With(slog.String(fnName, getAlbumsFn))
+
+	log.DebugContext(ctx, "albums pagination", "pagination", pag)
+
+	albums, err := s.store.Albums(ctx, pag)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to get albums", sl.Err(err))
+		return nil, err
+	}
+
+	if len(albums) == 0 {
+		log.InfoContext(ctx, "albums not found")
+		return nil, errs.NoAlbums()
+	}
+
+	log.InfoContext(ctx, "get albums OK")
+
+	return albums, nil
+}
+
+func (s *Service) GetAlbum(ctx context.Context, id int) (*types.Album, error) {
+	log := s.log.With(slog.String(fnName, getAlbumFn), slog.Int("albumID", id))
+
+	album, err := s.store.Album(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.InfoContext(ctx, "album not found")
+			return nil, errs.AlbumNotFound()
+		}
+		log.ErrorContext(ctx, "failed to get album", sl.Err(err))
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "get album OK")
+
+	return album, nil
+}
+
+func (s *Service) AddAlbum(ctx context.Context, req *types.AlbumRequest) (*types.Album, error) {
+	log := s.log.With(slog.String(fnName, addAlbumFn))
+
+	album, err := newAlbum(req)
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := s.store.AddAlbum(ctx, album)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to add album", sl.Err(err))
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "add album OK", "albumID", added.ID)
+
+	return added, nil
+}
+
+func (s *Service) UpdateAlbum(ctx context.Context, id int, req *types.AlbumRequest) error {
+	log := s.log.With(slog.String(fnName, updateAlbumFn), slog.Int("albumID", id))
+
+	album, err := newAlbum(req)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.UpdateAlbum(ctx, id, album); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.InfoContext(ctx, "album not found")
+			return errs.AlbumNotFound()
+		}
+		log.ErrorContext(ctx, "failed to update album", sl.Err(err))
+		return err
+	}
+
+	log.InfoContext(ctx, "update album OK")
+
+	return nil
+}
+
+func (s *Service) DeleteAlbum(ctx context.Context, id int) error {
+	log := s.log.With(slog.String(fnName, deleteAlbumFn), slog.Int("albumID", id))
+
+	if err := s.store.DeleteAlbum(ctx, id); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			log.InfoContext(ctx, "album not found")
+			return errs.AlbumNotFound()
+		case errors.Is(err, storage.ErrInUse):
+			log.InfoContext(ctx, "album still has songs")
+			return errs.AlbumHasSongs()
+		}
+		log.ErrorContext(ctx, "failed to delete album", sl.Err(err))
+		return err
+	}
+
+	log.InfoContext(ctx, "delete album OK")
+
+	return nil
+}
+
+func (s *Service) GetAlbumTracks(ctx context.Context, id int) ([]*types.Song, error) {
+	log := s.log.With(slog.String(fnName, getAlbumTracksFn), slog.Int("albumID", id))
+
+	if _, err := s.GetAlbum(ctx, id); err != nil {
+		return nil, err
+	}
+
+	tracks, err := s.store.AlbumTracks(ctx, id)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to get album tracks", sl.Err(err))
+		return nil, err
+	}
+
+	if len(tracks) == 0 {
+		log.InfoContext(ctx, "album has no tracks")
+		return nil, errs.NoSongs()
+	}
+
+	log.InfoContext(ctx, "get album tracks OK")
+
+	return tracks, nil
+}
+
+// checkTrack validates the album placement of a song: a track number needs
+// an album, and the album has to exist.
+func (s *Service) checkTrack(ctx context.Context, albumID, trackNumber *int) error {
+	if trackNumber != nil && (albumID == nil || *trackNumber <= 0) {
+		return errs.InvalidTrackNumber()
+	}
+
+	if albumID == nil {
+		return nil
+	}
+
+	_, err := s.GetAlbum(ctx, *albumID)
+
+	return err
+}
+
+func newAlbum(req *types.AlbumRequest) (*types.Album, error) {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return nil, errs.EmptyAlbumTitle()
+	}
+
+	group := normalizeName(req.Group)
+	if group == "" {
+		return nil, errs.EmptyGroupName()
+	}
+
+	releaseDate, err := time.Parse(lib.Layout, req.ReleaseDate)
+	if err != nil {
+		return nil, errs.InvalidDate()
+	}
+
+	album := &types.Album{
+		Title:       title,
+		Group:       group,
+		ReleaseDate: releaseDate,
+		CoverLink:   req.CoverLink,
+	}
+
+	return album, nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/logger/sl"
+	"github.com/erknas/song-library/internal/metadata"
+	"github.com/erknas/song-library/internal/types"
+)
+
+const invalidateDetailsCacheFn = "InvalidateDetailsCache"
+
+// InvalidateDetailsCache drops the cached details of the song from group,
+// or the whole cache if both are empty.
+func (s *Service) InvalidateDetailsCache(ctx context.Context, song, group string) (*types.CacheInvalidation, error) {
+	log := s.log.With(slog.String(fnName, invalidateDetailsCacheFn))
+
+	if s.cache == nil {
+		return nil, errs.DetailsCacheDisabled()
+	}
+
+	song, group = strings.TrimSpace(song), strings.TrimSpace(group)
+
+	if song == "" && group == "" {
+		n, err := s.cache.Clear(ctx)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to clear details cache", sl.Err(err))
+			return nil, err
+		}
+
+		log.InfoContext(ctx, "clear details cache OK", "invalidated", n)
+
+		return &types.CacheInvalidation{Invalidated: n}, nil
+	}
+
+	if song == "" || group == "" {
+		return nil, errs.InvalidCacheKey()
+	}
+
+	ok, err := s.cache.Delete(ctx, metadata.DetailsKey(song, group))
+	if err != nil {
+		log.ErrorContext(ctx, "failed to invalidate cached details", sl.Err(err))
+		return nil, err
+	}
+
+	inv := &types.CacheInvalidation{}
+	if ok {
+		inv.Invalidated = 1
+	}
+
+	log.InfoContext(ctx, "invalidate cached details OK", "song", song, "group", group, "invalidated", inv.Invalidated)
+
+	return inv, nil
+}
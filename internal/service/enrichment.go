@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/erknas/song-library/internal/config"
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/logger"
+	"github.com/erknas/song-library/internal/logger/sl"
+	"github.com/erknas/song-library/internal/metadata"
+	"github.com/erknas/song-library/internal/storage"
+	"github.com/erknas/song-library/internal/types"
+)
+
+const (
+	getSongEnrichmentFn   = "GetSongEnrichment"
+	getEnrichmentJobsFn   = "GetEnrichmentJobs"
+	retrySongEnrichmentFn = "RetrySongEnrichment"
+	runEnrichmentFn       = "RunEnrichment"
+	runEnrichmentJobFn    = "runEnrichmentJob"
+
+	// enrichmentAuthor is the author of the revisions enrichment records.
+	enrichmentAuthor = "enrichment"
+	// enrichmentLease hides a claimed job from other workers while it
+	// runs. It is well above the time one attempt can take.
+	enrichmentLease = time.Minute
+	// maxRetryDelay caps the backoff between attempts.
+	maxRetryDelay = time.Hour
+)
+
+func (s *Service) GetSongEnrichment(ctx context.Context, id int) (*types.Enrichment, error) {
+	ctx = logger.WithSongID(ctx, id)
+	log := s.log.With(slog.String(fnName, getSongEnrichmentFn))
+
+	job, err := s.store.SongEnrichment(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.InfoContext(ctx, "song not found")
+			return nil, errs.SongNotFound()
+		}
+		log.ErrorContext(ctx, "failed to get song enrichment", sl.Err(err))
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "get song enrichment OK")
+
+	return job, nil
+}
+
+// GetEnrichmentJobs lists the songs that aren't enriched yet, optionally
+// only the pending or the failed ones.
+func (s *Service) GetEnrichmentJobs(ctx context.Context, status string, pag types.Pagination) ([]*types.Enrichment, error) {
+	log := s.log.With(slog.String(fnName, getEnrichmentJobsFn))
+
+	switch status {
+	case "", types.EnrichmentPending, types.EnrichmentFailed:
+	default:
+		return nil, errs.InvalidEnrichmentStatus()
+	}
+
+	log.DebugContext(ctx, "enrichment jobs pagination", "status", status, "pagination", pag)
+
+	jobs, err := s.store.EnrichmentJobs(ctx, status, pag)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to get enrichment jobs", sl.Err(err))
+		return nil, err
+	}
+
+	if len(jobs) == 0 {
+		log.InfoContext(ctx, "enrichment jobs not found")
+		return nil, errs.NoEnrichmentJobs()
+	}
+
+	log.InfoContext(ctx, "get enrichment jobs OK")
+
+	return jobs, nil
+}
+
+// RetrySongEnrichment queues song id for enrichment again, typically after
+// its job failed.
+func (s *Service) RetrySongEnrichment(ctx context.Context, id int) (*types.Enrichment, error) {
+	ctx = logger.WithSongID(ctx, id)
+	log := s.log.With(slog.String(fnName, retrySongEnrichmentFn))
+
+	if err := s.store.RetryEnrichment(ctx, id); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			log.InfoContext(ctx, "song not found")
+			return nil, errs.SongNotFound()
+		case errors.Is(err, storage.ErrEnriched):
+			log.InfoContext(ctx, "song already enriched")
+			return nil, errs.SongEnriched()
+		default:
+			log.ErrorContext(ctx, "failed to retry song enrichment", sl.Err(err))
+			return nil, err
+		}
+	}
+
+	s.wakeEnrichment()
+
+	log.InfoContext(ctx, "retry song enrichment OK")
+
+	return s.GetSongEnrichment(ctx, id)
+}
+
+// RunEnrichment fetches the details of pending songs with a pool of
+// workers until ctx is done. Due jobs are claimed every poll interval and
+// right after a song is queued.
+func (s *Service) RunEnrichment(ctx context.Context, cfg config.EnrichmentConfig) {
+	if cfg.EnrichmentWorkers <= 0 || cfg.EnrichmentPollInterval <= 0 {
+		return
+	}
+
+	log := s.log.With(slog.String(fnName, runEnrichmentFn))
+
+	var (
+		jobs = make(chan *types.Enrichment)
+		wg   sync.WaitGroup
+	)
+
+	for range cfg.EnrichmentWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				s.runEnrichmentJob(ctx, job, cfg)
+			}
+		}()
+	}
+
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	ticker := time.NewTicker(cfg.EnrichmentPollInterval)
+	defer ticker.Stop()
+
+	for {
+		claimed, err := s.store.ClaimEnrichmentJobs(ctx, cfg.EnrichmentWorkers, enrichmentLease)
+		if err != nil && ctx.Err() == nil {
+			log.ErrorContext(ctx, "failed to claim enrichment jobs", sl.Err(err))
+		}
+
+		for _, job := range claimed {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- job:
+			}
+		}
+
+		// A full batch means more jobs may be due already.
+		if len(claimed) == cfg.EnrichmentWorkers {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+func (s *Service) runEnrichmentJob(ctx context.Context, job *types.Enrichment, cfg config.EnrichmentConfig) {
+	ctx = logger.WithSongID(ctx, job.SongID)
+	log := s.log.With(slog.String(fnName, runEnrichmentJobFn))
+
+	fetchCtx, cancel := context.WithTimeout(ctx, detailsTimeout)
+	details, err := s.fetchSongDetails(fetchCtx, &types.SongRequest{Song: job.Song, Group: job.Group})
+	cancel()
+
+	if err == nil {
+		err := s.store.CompleteEnrichment(ctx, job.SongID, details, types.WriteOptions{Author: enrichmentAuthor})
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			log.InfoContext(ctx, "song deleted or no longer pending")
+		case err != nil:
+			log.ErrorContext(ctx, "failed to complete enrichment", sl.Err(err))
+		default:
+			log.InfoContext(ctx, "enrich song OK")
+		}
+		return
+	}
+
+	// The job is claimed again once its lease runs out.
+	if ctx.Err() != nil {
+		return
+	}
+
+	attempt := job.Attempts + 1
+
+	// A song the providers have no details for won't get any on a retry,
+	// only transient failures are retried.
+	var retryAt *time.Time
+	if attempt < cfg.EnrichmentMaxAttempts && !errors.Is(err, metadata.ErrNotFound) {
+		t := time.Now().Add(retryDelay(cfg.EnrichmentRetryDelay, attempt))
+		retryAt = &t
+	}
+
+	log.WarnContext(ctx, "enrichment attempt failed", "attempt", attempt, "retryAt", retryAt, sl.Err(err))
+
+	if err := s.store.FailEnrichment(ctx, job.SongID, err.Error(), retryAt); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.ErrorContext(ctx, "failed to record enrichment failure", sl.Err(err))
+	}
+}
+
+// retryDelay doubles base for every attempt after the first, up to
+// maxRetryDelay.
+func retryDelay(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+func (s *Service) wakeEnrichment() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
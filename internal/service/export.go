@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/erknas/song-library/internal/logger/sl"
+	"github.com/erknas/song-library/internal/types"
+)
+
+const exportSongsFn = "ExportSongs"
+
+// ExportSongs passes every song matching fil to fn as it is read from
+// storage.
+func (s *Service) ExportSongs(ctx context.Context, fil types.Filter, fn func(*types.Song) error) error {
+	log := s.log.With(slog.String(fnName, exportSongsFn))
+
+	log.DebugContext(ctx, "export filter", "fil", fil)
+
+	n := 0
+	count := func(song *types.Song) error {
+		n++
+		return fn(song)
+	}
+
+	if err := s.store.ExportSongs(ctx, fil, count); err != nil {
+		log.ErrorContext(ctx, "failed to export songs", "exported", n, sl.Err(err))
+		return err
+	}
+
+	log.InfoContext(ctx, "export songs OK", "exported", n)
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/logger/sl"
+	"github.com/erknas/song-library/internal/storage"
+	"github.com/erknas/song-library/internal/types"
+)
+
+const (
+	getGroupsFn     = "GetGroups"
+	getGroupFn      = "GetGroup"
+	addGroupFn      = "AddGroup"
+	updateGroupFn   = "UpdateGroup"
+	deleteGroupFn   = "DeleteGroup"
+	getGroupSongsFn = "GetGroupSongs"
+)
+
+func (s *Service) GetGroups(ctx context.Context, pag types.Pagination) ([]*types.Group, error) {
+	log := s.log.With(slog.String(fnName, getGroupsFn))
+
+	log.DebugContext(ctx, "groups pagination", "pagination", pag)
+
+	groups, err := s.store.Groups(ctx, pag)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to get groups", sl.Err(err))
+		return nil, err
+	}
+
+	if len(groups) == 0 {
+		log.InfoContext(ctx, "groups not found")
+		return nil, errs.NoGroups()
+	}
+
+	log.InfoContext(ctx, "get groups OK")
+
+	return groups, nil
+}
+
+func (s *Service) GetGroup(ctx context.Context, id int) (*types.Group, error) {
+	log := s.log.With(slog.String(fnName, getGroupFn), slog.Int("groupID", id))
+
+	group, err := s.store.Group(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.InfoContext(ctx, "group not found")
+			return nil, errs.GroupNotFound()
+		}
+		log.ErrorContext(ctx, "failed to get group", sl.Err(err))
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "get group OK")
+
+	return group, nil
+}
+
+func (s *Service) AddGroup(ctx context.Context, req *types.GroupRequest) (*types.Group, error) {
+	log := s.log.With(slog.String(fnName, addGroupFn))
+
+	name := normalizeName(req.Name)
+	if name == "" {
+		return nil, errs.EmptyGroupName()
+	}
+
+	group, err := s.store.AddGroup(ctx, name)
+	if err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			log.InfoContext(ctx, "group already exists", "name", name)
+			return nil, errs.GroupAlreadyExists()
+		}
+		log.ErrorContext(ctx, "failed to add group", sl.Err(err))
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "add group OK", "groupID", group.ID)
+
+	return group, nil
+}
+
+func (s *Service) UpdateGroup(ctx context.Context, id int, req *types.GroupRequest) error {
+	log := s.log.With(slog.String(fnName, updateGroupFn), slog.Int("groupID", id))
+
+	name := normalizeName(req.Name)
+	if name == "" {
+		return errs.EmptyGroupName()
+	}
+
+	if err := s.store.UpdateGroup(ctx, id, name); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			log.InfoContext(ctx, "group not found")
+			return errs.GroupNotFound()
+		case errors.Is(err, storage.ErrAlreadyExists):
+			log.InfoContext(ctx, "group already exists", "name", name)
+			return errs.GroupAlreadyExists()
+		}
+		log.ErrorContext(ctx, "failed to update group", sl.Err(err))
+		return err
+	}
+
+	log.InfoContext(ctx, "update group OK")
+
+	return nil
+}
+
+func (s *Service) DeleteGroup(ctx context.Context, id int) error {
+	log := s.log.With(slog.String(fnName, deleteGroupFn), slog.Int("groupID", id))
+
+	if err := s.store.DeleteGroup(ctx, id); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			log.InfoContext(ctx, "group not found")
+			return errs.GroupNotFound()
+		case errors.Is(err, storage.ErrInUse):
+			log.InfoContext(ctx, "group still has songs")
+			return errs.GroupHasSongs()
+		}
+		log.ErrorContext(ctx, "failed to delete group", sl.Err(err))
+		return err
+	}
+
+	log.InfoContext(ctx, "delete group OK")
+
+	return nil
+}
+
+func (s *Service) GetGroupSongs(ctx context.Context, id int, q types.SongsQuery) (*types.SongsPage, error) {
+	log := s.log.With(slog.String(fnName, getGroupSongsFn), slog.Int("groupID", id))
+
+	if _, err := s.GetGroup(ctx, id); err != nil {
+		return nil, err
+	}
+
+	q.Filter.GroupID = id
+
+	page, err := s.GetSongs(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "get group songs OK")
+
+	return page, nil
+}
+
+// normalizeName trims name and collapses inner whitespace, so that "Muse"
+// and " Muse " name the same group.
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/lib"
+	"github.com/erknas/song-library/internal/logger/sl"
+	"github.com/erknas/song-library/internal/metadata"
+	"github.com/erknas/song-library/internal/storage"
+	"github.com/erknas/song-library/internal/types"
+)
+
+const (
+	importSongsFn = "ImportSongs"
+
+	// importWorkers bounds the concurrent calls to the details provider made
+	// by one import.
+	importWorkers = 8
+	// maxFieldLength is the length of the VARCHAR columns of songs and
+	// groups.
+	maxFieldLength = 255
+	// importStoreTimeout bounds storing the rows that are ready when the
+	// import runs out of time enriching the others.
+	importStoreTimeout = time.Second * 5
+)
+
+// ImportSongs adds the valid rows in one batch and reports the outcome of
+// every row. Rows without a release date are enriched through the details
+// provider first; a row that can't be enriched, or isn't by the time ctx is
+// done, fails on its own.
+//
+// onConflict decides what happens to a row naming a song that already
+// exists, or that an earlier row names: by default the row fails, skip
+// leaves the song as it is and update replaces it with the row.
+func (s *Service) ImportSongs(ctx context.Context, rows []types.ImportRow, onConflict string, opts types.WriteOptions) (*types.ImportReport, error) {
+	log := s.log.With(slog.String(fnName, importSongsFn))
+
+	switch onConflict {
+	case "":
+		onConflict = types.OnConflictError
+	case types.OnConflictError, types.OnConflictSkip, types.OnConflictUpdate:
+	default:
+		return nil, errs.InvalidOnConflict()
+	}
+
+	log.InfoContext(ctx, "import songs", "rows", len(rows), "onConflict", onConflict)
+
+	var (
+		report = &types.ImportReport{Results: make([]types.ImportResult, len(rows))}
+		songs  = make([]*types.Song, len(rows))
+		sem    = make(chan struct{}, importWorkers)
+		wg     sync.WaitGroup
+	)
+
+	for i, row := range rows {
+		report.Results[i].Line = row.Line
+
+		song, err := newImportSong(row)
+		if err != nil {
+			report.Results[i].Error = err.Error()
+			continue
+		}
+
+		if song.ReleaseDate != nil {
+			songs[i] = song
+			continue
+		}
+
+		if ctx.Err() != nil {
+			report.Results[i].Error = errs.APICallTimeout().Error()
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := s.enrichSong(ctx, song); err != nil {
+				report.Results[i].Error = err.Error()
+				return
+			}
+			songs[i] = song
+		}()
+	}
+
+	wg.Wait()
+
+	// The rows that are ready are still added. ctx is done, so they get a
+	// deadline of their own.
+	if ctx.Err() != nil {
+		log.WarnContext(ctx, "import timeout, adding the rows that are ready")
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.WithoutCancel(ctx), importStoreTimeout)
+		defer cancel()
+	}
+
+	var (
+		batch []*types.Song
+		index []int
+		// seen maps the song key of every row in batch to its position.
+		seen = make(map[string]int)
+		// repeats maps rows naming a song of an earlier row to the position
+		// of that row in batch.
+		repeats = make(map[int]int)
+		tracks  = newImportTracks(s)
+	)
+
+	for i, song := range songs {
+		if song == nil {
+			continue
+		}
+		if err := checkLengths(song); err != nil {
+			report.Results[i].Error = err.Error()
+			continue
+		}
+
+		key := songKey(song)
+
+		j, repeat := seen[key]
+
+		// Only a repeat that updates the song moves it to its track.
+		if !repeat || onConflict == types.OnConflictUpdate {
+			if err := tracks.claim(ctx, song); err != nil {
+				report.Results[i].Error = err.Error()
+				continue
+			}
+		}
+
+		if repeat {
+			switch onConflict {
+			case types.OnConflictError:
+				report.Results[i].Error = fmt.Sprintf("song repeats line %d", rows[index[j]].Line)
+				continue
+			case types.OnConflictUpdate:
+				batch[j] = song
+			}
+			repeats[i] = j
+			continue
+		}
+
+		seen[key] = len(batch)
+		batch = append(batch, song)
+		index = append(index, i)
+	}
+
+	if len(batch) > 0 {
+		imported, err := s.store.ImportSongs(ctx, batch, onConflict, opts)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to import songs", sl.Err(err))
+			return nil, trackError(err)
+		}
+
+		for j, song := range imported {
+			result := &report.Results[index[j]]
+			if song.Action == types.ImportSkipped && onConflict == types.OnConflictError {
+				result.Error = storage.DuplicateSongError{ID: song.ID}.Error()
+				continue
+			}
+			result.ID, result.Action = song.ID, song.Action
+		}
+
+		for i, j := range repeats {
+			report.Results[i].ID = imported[j].ID
+			report.Results[i].Action = types.ImportSkipped
+			if onConflict == types.OnConflictUpdate {
+				report.Results[i].Action = types.ImportUpdated
+			}
+		}
+	}
+
+	for _, result := range report.Results {
+		switch {
+		case result.Error != "":
+			report.Failed++
+		case result.Action == types.ImportAdded:
+			report.Imported++
+		case result.Action == types.ImportUpdated:
+			report.Updated++
+		case result.Action == types.ImportSkipped:
+			report.Skipped++
+		}
+	}
+
+	log.InfoContext(ctx, "import songs OK", "imported", report.Imported, "updated", report.Updated,
+		"skipped", report.Skipped, "failed", report.Failed)
+
+	return report, nil
+}
+
+// enrichSong fills in the release date of song from the details provider. Text
+// and link given in the import are kept.
+func (s *Service) enrichSong(ctx context.Context, song *types.Song) error {
+	ctx, cancel := context.WithTimeout(ctx, detailsTimeout)
+	defer cancel()
+
+	details, err := s.fetchSongDetails(ctx, &types.SongRequest{Song: song.Song, Group: song.Group})
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errs.APICallTimeout()
+		}
+		if errors.Is(err, metadata.ErrNotFound) {
+			return metadata.ErrNotFound
+		}
+		return fmt.Errorf("fetch song details: %w", err)
+	}
+
+	song.ReleaseDate = details.ReleaseDate
+	if song.Text == "" {
+		song.Text = details.Text
+	}
+	if song.Link == "" {
+		song.Link = details.Link
+	}
+
+	return nil
+}
+
+func newImportSong(row types.ImportRow) (*types.Song, error) {
+	if row.Err != nil {
+		return nil, row.Err
+	}
+
+	song := &types.Song{
+		Song:        strings.TrimSpace(row.Song),
+		Group:       normalizeName(row.Group),
+		Text:        row.Text,
+		Link:        strings.TrimSpace(row.Link),
+		AlbumID:     row.AlbumID,
+		TrackNumber: row.TrackNumber,
+	}
+
+	if song.Song == "" {
+		return nil, errs.EmptySongName()
+	}
+
+	if song.Group == "" {
+		return nil, errs.EmptyGroupName()
+	}
+
+	if date := strings.TrimSpace(row.ReleaseDate); date != "" {
+		releaseDate, err := time.Parse(lib.Layout, date)
+		if err != nil {
+			return nil, errs.InvalidDate()
+		}
+		song.ReleaseDate = &releaseDate
+	}
+
+	return song, nil
+}
+
+// importTracks checks the album placement of the rows of an import against
+// the songs already on their albums and against each other, so that a taken
+// track number fails its row instead of the whole batch.
+type importTracks struct {
+	s *Service
+	// albums holds the outcome of looking up every album named so far.
+	albums map[int]error
+	// taken maps an album ID and track number to the key of its song.
+	taken map[[2]int]string
+}
+
+func newImportTracks(s *Service) *importTracks {
+	return &importTracks{
+		s:      s,
+		albums: make(map[int]error),
+		taken:  make(map[[2]int]string),
+	}
+}
+
+// claim takes the track of song on its album. A song may take a track it
+// already has.
+func (t *importTracks) claim(ctx context.Context, song *types.Song) error {
+	albumID, trackNumber := song.AlbumID, song.TrackNumber
+	if trackNumber != nil && (albumID == nil || *trackNumber <= 0) {
+		return errs.InvalidTrackNumber()
+	}
+
+	if albumID == nil {
+		return nil
+	}
+
+	err, ok := t.albums[*albumID]
+	if !ok {
+		err = t.load(ctx, *albumID)
+		t.albums[*albumID] = err
+	}
+	if err != nil || trackNumber == nil {
+		return err
+	}
+
+	track, key := [2]int{*albumID, *trackNumber}, songKey(song)
+	if holder, ok := t.taken[track]; ok && holder != key {
+		return errs.TrackNumberTaken()
+	}
+	t.taken[track] = key
+
+	return nil
+}
+
+// load looks up album id and the tracks taken on it.
+func (t *importTracks) load(ctx context.Context, id int) error {
+	if _, err := t.s.GetAlbum(ctx, id); err != nil {
+		return err
+	}
+
+	songs, err := t.s.store.AlbumTracks(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, song := range songs {
+		if song.TrackNumber != nil {
+			t.taken[[2]int{id, *song.TrackNumber}] = songKey(song)
+		}
+	}
+
+	return nil
+}
+
+// songKey identifies a song the way the unique index on songs does, by its
+// name and group regardless of case and whitespace.
+func songKey(song *types.Song) string {
+	return strings.ToLower(normalizeName(song.Group)) + "\n" + strings.ToLower(normalizeName(song.Song))
+}
+
+// checkLengths rejects a song that doesn't fit its columns, which would
+// otherwise fail the whole batch.
+func checkLengths(song *types.Song) error {
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"song", song.Song},
+		{"group", song.Group},
+		{"link", song.Link},
+	}
+
+	for _, f := range fields {
+		if utf8.RuneCountInString(f.value) > maxFieldLength {
+			return errs.FieldTooLong(f.name, maxFieldLength)
+		}
+	}
+
+	return nil
+}
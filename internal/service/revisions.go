@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/lib"
+	"github.com/erknas/song-library/internal/logger"
+	"github.com/erknas/song-library/internal/logger/sl"
+	"github.com/erknas/song-library/internal/storage"
+	"github.com/erknas/song-library/internal/types"
+)
+
+const (
+	getSongRevisionsFn    = "GetSongRevisions"
+	getSongRevisionFn     = "GetSongRevision"
+	diffSongRevisionsFn   = "DiffSongRevisions"
+	restoreSongRevisionFn = "RestoreSongRevision"
+)
+
+func (s *Service) GetSongRevisions(ctx context.Context, id int, pag types.Pagination) ([]*types.Revision, error) {
+	ctx = logger.WithSongID(ctx, id)
+	log := s.log.With(slog.String(fnName, getSongRevisionsFn))
+
+	log.DebugContext(ctx, "revisions pagination", "pagination", pag)
+
+	revisions, err := s.store.SongRevisions(ctx, id, pag)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to get song revisions", sl.Err(err))
+		return nil, err
+	}
+
+	if len(revisions) == 0 {
+		log.InfoContext(ctx, "song revisions not found")
+		return nil, errs.NoRevisions()
+	}
+
+	log.InfoContext(ctx, "get song revisions OK")
+
+	return revisions, nil
+}
+
+func (s *Service) GetSongRevision(ctx context.Context, id, revID int) (*types.Revision, error) {
+	ctx = logger.WithSongID(ctx, id)
+	log := s.log.With(slog.String(fnName, getSongRevisionFn), slog.Int("revisionID", revID))
+
+	rev, err := s.store.SongRevision(ctx, id, revID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.InfoContext(ctx, "song revision not found")
+			return nil, errs.RevisionNotFound()
+		}
+		log.ErrorContext(ctx, "failed to get song revision", sl.Err(err))
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "get song revision OK")
+
+	return rev, nil
+}
+
+func (s *Service) DiffSongRevisions(ctx context.Context, id, fromID, toID int) (*types.RevisionDiff, error) {
+	ctx = logger.WithSongID(ctx, id)
+	log := s.log.With(slog.String(fnName, diffSongRevisionsFn))
+
+	from, err := s.GetSongRevision(ctx, id, fromID)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := s.GetSongRevision(ctx, id, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "diff song revisions OK", "from", fromID, "to", toID)
+
+	return &types.RevisionDiff{
+		From:   from.ID,
+		To:     to.ID,
+		Fields: diffFields(from, to),
+		Verses: diffVerses(verses(from.Text), verses(to.Text)),
+	}, nil
+}
+
+// RestoreSongRevision puts the song back to revision revID. A deleted song
+// is added again under its old ID.
+func (s *Service) RestoreSongRevision(ctx context.Context, id, revID int, opts types.WriteOptions) (*types.Song, error) {
+	ctx = logger.WithSongID(ctx, id)
+	log := s.log.With(slog.String(fnName, restoreSongRevisionFn), slog.Int("revisionID", revID))
+
+	rev, err := s.GetSongRevision(ctx, id, revID)
+	if err != nil {
+		return nil, err
+	}
+
+	song := rev.SongAt()
+
+	if err := s.checkTrack(ctx, song.AlbumID, song.TrackNumber); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkIfMatch(ctx, id, &opts); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.RestoreSong(ctx, id, song, opts); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			log.InfoContext(ctx, "song not found")
+			return nil, errs.SongNotFound()
+		case errors.Is(err, storage.ErrVersionMismatch):
+			log.InfoContext(ctx, "song version changed", "version", opts.IfVersion)
+			return nil, errs.PreconditionFailed()
+		}
+		log.ErrorContext(ctx, "failed to restore song revision", sl.Err(err))
+		return nil, songError(err)
+	}
+
+	log.InfoContext(ctx, "restore song revision OK")
+
+	return s.GetSong(ctx, id)
+}
+
+// diffFields lists the metadata fields that differ between two revisions.
+func diffFields(from, to *types.Revision) []types.FieldChange {
+	var changes []types.FieldChange
+
+	add := func(field string, a, b any) {
+		changes = append(changes, types.FieldChange{Field: field, From: a, To: b})
+	}
+
+	if from.Song != to.Song {
+		add("song", from.Song, to.Song)
+	}
+	if from.Group != to.Group {
+		add("group", from.Group, to.Group)
+	}
+	if !equalDates(from.ReleaseDate, to.ReleaseDate) {
+		add("releaseDate", formatDate(from.ReleaseDate), formatDate(to.ReleaseDate))
+	}
+	if from.Link != to.Link {
+		add("link", from.Link, to.Link)
+	}
+	if !equalInts(from.AlbumID, to.AlbumID) {
+		add("albumId", from.AlbumID, to.AlbumID)
+	}
+	if !equalInts(from.TrackNumber, to.TrackNumber) {
+		add("trackNumber", from.TrackNumber, to.TrackNumber)
+	}
+
+	return changes
+}
+
+// diffVerses computes a verse by verse diff of two texts from their
+// longest common subsequence of verses.
+func diffVerses(a, b []string) []types.VerseChange {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	changes := make([]types.VerseChange, 0, max(len(a), len(b)))
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			changes = append(changes, types.VerseChange{Op: types.VerseEqual, Verse: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			changes = append(changes, types.VerseChange{Op: types.VerseDelete, Verse: a[i]})
+			i++
+		default:
+			changes = append(changes, types.VerseChange{Op: types.VerseInsert, Verse: b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		changes = append(changes, types.VerseChange{Op: types.VerseDelete, Verse: a[i]})
+	}
+	for ; j < len(b); j++ {
+		changes = append(changes, types.VerseChange{Op: types.VerseInsert, Verse: b[j]})
+	}
+
+	return changes
+}
+
+func verses(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n\n")
+}
+
+func equalInts(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalDates(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// formatDate formats a release date the way the API accepts it, or
+// returns nil for a missing one.
+func formatDate(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.Format(lib.Layout)
+}
@@ -2,10 +2,8 @@ package service
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
 	"log/slog"
-	"net/http"
 	"strings"
 	"time"
 
@@ -13,6 +11,7 @@ import (
 	"github.com/erknas/song-library/internal/lib"
 	"github.com/erknas/song-library/internal/logger"
 	"github.com/erknas/song-library/internal/logger/sl"
+	"github.com/erknas/song-library/internal/metadata"
 	"github.com/erknas/song-library/internal/storage"
 	"github.com/erknas/song-library/internal/types"
 )
@@ -20,102 +19,114 @@ import (
 const (
 	fnName             = "func"
 	getSongsFn         = "GetSongs"
+	getSongFn          = "GetSong"
+	searchSongsFn      = "SearchSongs"
 	getSongTextFn      = "GetSongText"
 	deleteSongFn       = "DeleteSong"
 	updateSongFn       = "UpdateSong"
+	patchSongFn        = "PatchSong"
 	addSongFn          = "AddSong"
 	fetchSongDetailsFn = "fetchSongDetails"
-)
+	getHealthFn        = "GetHealth"
 
-type Response struct {
-	types.Song
-	err error
-}
+	// detailsTimeout bounds a details lookup, retries included.
+	detailsTimeout = time.Second * 10
+)
 
 type Service struct {
-	url   string
+	details metadata.DetailsProvider
+	// cache is the details cache behind details, nil if caching is off.
+	cache metadata.DetailsCache
 	log   *slog.Logger
 	store storage.Storer
+	// wake tells the enrichment dispatcher that a job was queued.
+	wake chan struct{}
 }
 
-func New(url string, log *slog.Logger, store storage.Storer) *Service {
+func New(details metadata.DetailsProvider, cache metadata.DetailsCache, log *slog.Logger, store storage.Storer) *Service {
 	return &Service{
-		url:   url,
-		log:   log,
-		store: store,
+		details: details,
+		cache:   cache,
+		log:     log,
+		store:   store,
+		wake:    make(chan struct{}, 1),
 	}
 }
 
-func (s *Service) GetSongs(ctx context.Context, pag types.Pagination, fil types.Filter) ([]*types.Song, error) {
+func (s *Service) GetSongs(ctx context.Context, q types.SongsQuery) (*types.SongsPage, error) {
 	log := s.log.With(slog.String(fnName, getSongsFn))
 
-	page := (pag.Page - 1) * pag.Size
+	log.DebugContext(ctx, "songs query", "query", q)
 
-	pag = types.Pagination{
-		Page: page,
-		Size: pag.Size,
+	if c := q.Pagination.Cursor; c != nil && c.Sort != q.SortSignature() {
+		log.InfoContext(ctx, "cursor does not match sort", "cursor", c.Sort, "sort", q.SortSignature())
+		return nil, errs.InvalidCursor()
 	}
 
-	log.DebugContext(ctx, "pagination params", "pagination", pag)
+	page, err := s.store.Songs(ctx, q)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to get songs", sl.Err(err))
+		return nil, err
+	}
 
-	if fil.Song == "" && fil.Group == "" && fil.Date == nil {
-		songs, err := s.store.Songs(ctx, pag)
-		if err != nil {
-			log.ErrorContext(ctx, "failed to get songs from w/o filters", sl.Err(err))
-			return nil, err
-		}
+	if len(page.Songs) == 0 {
+		log.InfoContext(ctx, "songs not found", "fil", q.Filter)
+		return nil, errs.NoSongs()
+	}
 
-		if len(songs) == 0 {
-			log.InfoContext(ctx, "songs not found")
-			return nil, errs.NoSongs()
-		}
+	log.InfoContext(ctx, "get songs OK")
+
+	return page, nil
+}
 
-		log.InfoContext(ctx, "get songs OK")
+func (s *Service) GetSong(ctx context.Context, id int) (*types.Song, error) {
+	ctx = logger.WithSongID(ctx, id)
+	log := s.log.With(slog.String(fnName, getSongFn))
 
-		return songs, nil
+	song, err := s.store.SongByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.InfoContext(ctx, "song not found")
+			return nil, errs.SongNotFound()
+		}
+		log.ErrorContext(ctx, "failed to get song", sl.Err(err))
+		return nil, err
 	}
 
-	query := "SELECT id, song, group_name, release_date, text, link FROM songs WHERE true"
-	argsCount := 1
-	var args []any
+	log.InfoContext(ctx, "get song OK")
 
-	if fil.Song != "" {
-		query += fmt.Sprintf(" AND LOWER(song)=LOWER($%d)", argsCount)
-		args = append(args, fil.Song)
-		argsCount++
-	}
+	return song, nil
+}
 
-	if fil.Group != "" {
-		query += fmt.Sprintf(" AND LOWER(group_name)=LOWER($%d)", argsCount)
-		args = append(args, fil.Group)
-		argsCount++
-	}
+func (s *Service) SearchSongs(ctx context.Context, pag types.Pagination, query string) ([]*types.SearchResult, error) {
+	log := s.log.With(slog.String(fnName, searchSongsFn))
 
-	if fil.Date != nil {
-		query += fmt.Sprintf(" AND release_date=$%d", argsCount)
-		args = append(args, *fil.Date)
-		argsCount++
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errs.EmptySearchQuery()
 	}
 
-	query += fmt.Sprintf(" ORDER BY id LIMIT $%d OFFSET $%d", argsCount, argsCount+1)
-	args = append(args, pag.Size, pag.Page)
+	q := types.SearchQuery{
+		Query:      query,
+		Pagination: pag,
+	}
 
-	log.DebugContext(ctx, "get songs with filters", "args", args)
+	log.DebugContext(ctx, "search query", "query", q)
 
-	songs, err := s.store.SongsByFilters(ctx, query, args)
+	results, err := s.store.SearchSongs(ctx, q)
 	if err != nil {
-		log.ErrorContext(ctx, "failed to get songs with filters", sl.Err(err))
+		log.ErrorContext(ctx, "failed to search songs", sl.Err(err))
 		return nil, err
 	}
 
-	if len(songs) == 0 {
-		log.InfoContext(ctx, "songs not found with filters", "fil", fil)
+	if len(results) == 0 {
+		log.InfoContext(ctx, "songs not found by text", "query", query)
 		return nil, errs.NoSongs()
 	}
 
-	log.InfoContext(ctx, "get songs with filters OK")
+	log.InfoContext(ctx, "search songs OK")
 
-	return songs, nil
+	return results, nil
 }
 
 func (s *Service) GetSongText(ctx context.Context, pag types.Pagination, id int) ([]string, error) {
@@ -126,6 +137,10 @@ func (s *Service) GetSongText(ctx context.Context, pag types.Pagination, id int)
 
 	text, err := s.store.SongText(ctx, id)
 	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.InfoContext(ctx, "song not found")
+			return nil, errs.SongNotFound()
+		}
 		log.ErrorContext(ctx, "failed to get song text", sl.Err(err))
 		return nil, err
 	}
@@ -158,11 +173,23 @@ func (s *Service) GetSongText(ctx context.Context, pag types.Pagination, id int)
 	return verses[pageStart:pageEnd], nil
 }
 
-func (s *Service) DeleteSong(ctx context.Context, id int) error {
+func (s *Service) DeleteSong(ctx context.Context, id int, opts types.WriteOptions) error {
 	ctx = logger.WithSongID(ctx, id)
 	log := s.log.With(slog.String(fnName, deleteSongFn))
 
-	if err := s.store.DeleteSong(ctx, id); err != nil {
+	if err := s.checkIfMatch(ctx, id, &opts); err != nil {
+		return err
+	}
+
+	if err := s.store.DeleteSong(ctx, id, opts); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			log.InfoContext(ctx, "song not found")
+			return errs.SongNotFound()
+		case errors.Is(err, storage.ErrVersionMismatch):
+			log.InfoContext(ctx, "song version changed", "version", opts.IfVersion)
+			return errs.PreconditionFailed()
+		}
 		log.ErrorContext(ctx, "failed to delete song", sl.Err(err))
 		return err
 	}
@@ -172,128 +199,302 @@ func (s *Service) DeleteSong(ctx context.Context, id int) error {
 	return nil
 }
 
-func (s *Service) UpdateSong(ctx context.Context, id int, req *types.UpdateSongRequest) error {
+func (s *Service) UpdateSong(ctx context.Context, id int, req *types.UpdateSongRequest, opts types.WriteOptions) (*types.Song, error) {
 	ctx = logger.WithSongID(ctx, id)
 	log := s.log.With(slog.String(fnName, updateSongFn))
 
+	name := strings.TrimSpace(req.Song)
+	if name == "" {
+		return nil, errs.EmptySongName()
+	}
+
+	group := normalizeName(req.Group)
+	if group == "" {
+		return nil, errs.EmptyGroupName()
+	}
+
 	releaseDate, err := time.Parse(lib.Layout, req.ReleaseDate)
 	if err != nil {
 		log.ErrorContext(ctx, "failed to parse date", sl.Err(err))
-		return errs.InvalidDate()
+		return nil, errs.InvalidDate()
+	}
+
+	if err := s.checkTrack(ctx, req.AlbumID, req.TrackNumber); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkIfMatch(ctx, id, &opts); err != nil {
+		return nil, err
 	}
 
 	song := &types.Song{
-		Song:        req.Song,
-		Group:       req.Group,
-		ReleaseDate: releaseDate,
+		Song:        name,
+		Group:       group,
+		ReleaseDate: &releaseDate,
 		Text:        req.Text,
 		Link:        req.Link,
+		AlbumID:     req.AlbumID,
+		TrackNumber: req.TrackNumber,
 	}
 
 	log.DebugContext(ctx, "to update", "req", req)
 
-	if err := s.store.UpdateSong(ctx, id, song); err != nil {
+	if err := s.store.UpdateSong(ctx, id, song, opts); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			log.InfoContext(ctx, "song not found")
+			return nil, errs.SongNotFound()
+		case errors.Is(err, storage.ErrVersionMismatch):
+			log.InfoContext(ctx, "song version changed", "version", opts.IfVersion)
+			return nil, errs.PreconditionFailed()
+		}
 		log.ErrorContext(ctx, "failed to update song", sl.Err(err))
+		return nil, songError(err)
 	}
 
 	log.InfoContext(ctx, "update song OK")
 
-	return nil
+	return s.GetSong(ctx, id)
 }
 
-func (s *Service) AddSong(ctx context.Context, req *types.SongRequest) error {
-	log := s.log.With(slog.String(fnName, addSongFn))
+func (s *Service) PatchSong(ctx context.Context, id int, req *types.PatchSongRequest, opts types.WriteOptions) (*types.Song, error) {
+	ctx = logger.WithSongID(ctx, id)
+	log := s.log.With(slog.String(fnName, patchSongFn))
 
-	log.DebugContext(ctx, "song request", "req", req)
+	patch, err := newSongPatch(req)
+	if err != nil {
+		return nil, err
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
-	defer cancel()
+	log.DebugContext(ctx, "to patch", "patch", patch)
 
-	respch := make(chan Response)
+	song, err := s.GetSong(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
-	go func() {
-		song, err := s.fetchSongDetails(ctx, req)
-		if err != nil {
-			respch <- Response{err: err}
-			return
-		}
-		respch <- Response{
-			Song: *song,
-			err:  err,
-		}
-	}()
-
-	select {
-	case <-ctx.Done():
-		log.WarnContext(ctx, "api call timeout")
-		return errs.APICallTimeout()
-	case resp := <-respch:
-		if resp.err != nil {
-			log.ErrorContext(ctx, "failed to fetch song details", sl.Err(resp.err))
-			return resp.err
-		}
-		if err := s.store.AddSong(ctx, &resp.Song); err != nil {
-			log.ErrorContext(ctx, "failed to add song", sl.Err(err))
-			return err
+	if err := matchSong(song, &opts); err != nil {
+		return nil, err
+	}
+
+	if patch.Empty() {
+		return song, nil
+	}
+
+	// The album and track number are validated together, so a patch
+	// changing one of them is checked against the current other one.
+	patched := patch.Apply(song)
+	if err := s.checkTrack(ctx, patched.AlbumID, patched.TrackNumber); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.PatchSong(ctx, id, patch, opts); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			log.InfoContext(ctx, "song not found")
+			return nil, errs.SongNotFound()
+		case errors.Is(err, storage.ErrVersionMismatch):
+			log.InfoContext(ctx, "song version changed", "version", opts.IfVersion)
+			return nil, errs.PreconditionFailed()
 		}
-		return nil
+		log.ErrorContext(ctx, "failed to patch song", sl.Err(err))
+		return nil, songError(err)
 	}
+
+	log.InfoContext(ctx, "patch song OK")
+
+	return s.GetSong(ctx, id)
 }
 
-func (s *Service) fetchSongDetails(ctx context.Context, req *types.SongRequest) (*types.Song, error) {
-	log := s.log.With(fnName, fetchSongDetailsFn)
+// checkIfMatch compares the If-Match header in opts with the ETag of song
+// id and pins the change to the version it was computed from.
+func (s *Service) checkIfMatch(ctx context.Context, id int, opts *types.WriteOptions) error {
+	if opts.IfMatch == "" {
+		return nil
+	}
 
-	u, err := lib.ParseURL(s.url, req)
+	song, err := s.GetSong(ctx, id)
 	if err != nil {
-		log.ErrorContext(ctx, "failed to parse URL", sl.Err(err))
-		return nil, err
+		return err
 	}
 
-	log.InfoContext(ctx, "parsed URL", "url", u)
+	return matchSong(song, opts)
+}
 
-	client := &http.Client{}
+func matchSong(song *types.Song, opts *types.WriteOptions) error {
+	if opts.IfMatch == "" {
+		return nil
+	}
 
-	r, err := http.NewRequest(http.MethodGet, u, nil)
-	if err != nil {
-		return nil, err
+	if !lib.ETagMatches(opts.IfMatch, lib.ETag(song)) {
+		return errs.PreconditionFailed()
 	}
 
-	resp, err := client.Do(r)
-	if err != nil {
-		log.ErrorContext(ctx, "failed to make request to API", "url", u)
-		return nil, err
+	opts.IfVersion = song.Version
+
+	return nil
+}
+
+// songError maps storage errors caused by a song clashing with another one
+// by name or by album placement.
+func songError(err error) error {
+	var dup storage.DuplicateSongError
+	switch {
+	case errors.As(err, &dup):
+		return errs.SongAlreadyExists(dup.ID)
+	case errors.Is(err, storage.ErrDuplicateSong):
+		return errs.SongAlreadyExists(0)
 	}
-	defer resp.Body.Close()
+	return trackError(err)
+}
 
-	log.InfoContext(ctx, "response status code", "code", resp.StatusCode)
+// trackError maps storage errors caused by the album placement of a song.
+func trackError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrAlreadyExists):
+		return errs.TrackNumberTaken()
+	case errors.Is(err, storage.ErrInUse):
+		return errs.AlbumNotFound()
+	}
+	return err
+}
+
+// AddSong stores the song right away and queues it for enrichment, which
+// fetches its details in the background.
+func (s *Service) AddSong(ctx context.Context, req *types.SongRequest, opts types.WriteOptions) (*types.Song, error) {
+	log := s.log.With(slog.String(fnName, addSongFn))
+
+	log.DebugContext(ctx, "song request", "req", req)
 
-	if resp.StatusCode != http.StatusOK {
-		log.ErrorContext(ctx, "unexpected status code", "status code", resp.StatusCode)
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	req.Song = strings.TrimSpace(req.Song)
+	if req.Song == "" {
+		return nil, errs.EmptySongName()
 	}
 
-	details := new(types.Details)
+	req.Group = normalizeName(req.Group)
+	if req.Group == "" {
+		return nil, errs.EmptyGroupName()
+	}
 
-	if err := json.NewDecoder(resp.Body).Decode(details); err != nil {
-		log.ErrorContext(ctx, "failed to decode JSON response", sl.Err(err))
+	if err := s.checkTrack(ctx, req.AlbumID, req.TrackNumber); err != nil {
 		return nil, err
 	}
 
-	releaseDate, err := time.Parse(lib.Layout, details.ReleaseDate)
+	song := &types.Song{
+		Song:             req.Song,
+		Group:            req.Group,
+		AlbumID:          req.AlbumID,
+		TrackNumber:      req.TrackNumber,
+		EnrichmentStatus: types.EnrichmentPending,
+	}
+
+	added, err := s.store.AddSong(ctx, song, opts)
 	if err != nil {
-		log.ErrorContext(ctx, "failed to parse date", sl.Err(err))
+		log.ErrorContext(ctx, "failed to add song", sl.Err(err))
+		return nil, songError(err)
+	}
+
+	s.wakeEnrichment()
+
+	log.InfoContext(ctx, "add song OK", "id", added.ID)
+
+	return added, nil
+}
+
+// GetHealth reports whether the details providers are up. The service keeps
+// working while they are down, with new songs waiting for enrichment.
+func (s *Service) GetHealth(ctx context.Context) *types.Health {
+	log := s.log.With(slog.String(fnName, getHealthFn))
+
+	health := &types.Health{
+		Status:    types.HealthOK,
+		Providers: s.details.Health(),
+	}
+
+	for _, p := range health.Providers {
+		if !p.Up {
+			health.Status = types.HealthDegraded
+		}
+	}
+
+	log.DebugContext(ctx, "health", "status", health.Status)
+
+	return health
+}
+
+func (s *Service) fetchSongDetails(ctx context.Context, req *types.SongRequest) (*types.Song, error) {
+	log := s.log.With(fnName, fetchSongDetailsFn)
+
+	details, err := s.details.SongDetails(ctx, req)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to fetch song details", sl.Err(err))
 		return nil, err
 	}
 
 	song := &types.Song{
 		Song:        req.Song,
-		Group:       req.Group,
-		ReleaseDate: releaseDate,
+		Group:       normalizeName(req.Group),
+		ReleaseDate: details.ReleaseDate,
 		Text:        details.Text,
 		Link:        details.Link,
 	}
 
-	log.InfoContext(ctx, "fetch song details OK", "details", details)
+	log.InfoContext(ctx, "fetch song details OK")
 
 	return song, nil
 }
+
+// newSongPatch validates every member present in req. Only albumId and
+// trackNumber can be cleared with null.
+func newSongPatch(req *types.PatchSongRequest) (*types.SongPatch, error) {
+	patch := &types.SongPatch{
+		AlbumID:     req.AlbumID,
+		TrackNumber: req.TrackNumber,
+	}
+
+	required := []struct {
+		field string
+		opt   types.Optional[string]
+	}{
+		{"song", req.Song},
+		{"group", req.Group},
+		{"releaseDate", req.ReleaseDate},
+		{"text", req.Text},
+		{"link", req.Link},
+	}
+
+	for _, r := range required {
+		if r.opt.Set && r.opt.Value == nil {
+			return nil, errs.NullField(r.field)
+		}
+	}
+
+	if req.Song.Set {
+		song := strings.TrimSpace(*req.Song.Value)
+		if song == "" {
+			return nil, errs.EmptySongName()
+		}
+		patch.Song = &song
+	}
+
+	if req.Group.Set {
+		group := normalizeName(*req.Group.Value)
+		if group == "" {
+			return nil, errs.EmptyGroupName()
+		}
+		patch.Group = &group
+	}
+
+	if req.ReleaseDate.Set {
+		releaseDate, err := time.Parse(lib.Layout, *req.ReleaseDate.Value)
+		if err != nil {
+			return nil, errs.InvalidDate()
+		}
+		patch.ReleaseDate = &releaseDate
+	}
+
+	patch.Text = req.Text.Value
+	patch.Link = req.Link.Value
+
+	return patch, nil
+}
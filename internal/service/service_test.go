@@ -0,0 +1,744 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erknas/song-library/internal/config"
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/lib"
+	"github.com/erknas/song-library/internal/metadata"
+	"github.com/erknas/song-library/internal/storage"
+	"github.com/erknas/song-library/internal/types"
+)
+
+// newTestService returns a service over a memory store holding song 1,
+// "Uprising" by Muse, and song 2, "Creep" by Radiohead.
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	s := New(stubDetails{}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)), storage.NewMemoryStore())
+
+	for _, req := range []types.SongRequest{
+		{Song: "Uprising", Group: "Muse"},
+		{Song: "Creep", Group: "Radiohead"},
+	} {
+		if _, err := s.AddSong(context.Background(), &req, types.WriteOptions{}); err != nil {
+			t.Fatalf("AddSong(%q) error = %v", req.Song, err)
+		}
+	}
+
+	return s
+}
+
+// statusCode returns the HTTP status err is answered with, 0 for nil.
+func statusCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var apiErr errs.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+
+	return http.StatusInternalServerError
+}
+
+func patchText(text string) *types.PatchSongRequest {
+	return &types.PatchSongRequest{Text: types.Optional[string]{Set: true, Value: &text}}
+}
+
+func TestPatchSong(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantSong   string
+		wantGroup  string
+		wantText   string
+	}{
+		{"text", `{"text": "They will not force us"}`, 0, "Uprising", "Muse", "They will not force us"},
+		{"trimmed song", `{"song": "  Uprising (Live) "}`, 0, "Uprising (Live)", "Muse", ""},
+		{"normalized group", `{"group": " The   Muse "}`, 0, "Uprising", "The Muse", ""},
+		{"clear album", `{"albumId": null, "trackNumber": null}`, 0, "Uprising", "Muse", ""},
+		{"unknown member", `{"title": "Hysteria"}`, 0, "Uprising", "Muse", ""},
+		{"null song", `{"song": null}`, http.StatusBadRequest, "", "", ""},
+		{"null text", `{"text": null}`, http.StatusBadRequest, "", "", ""},
+		{"blank song", `{"song": " "}`, http.StatusBadRequest, "", "", ""},
+		{"blank group", `{"group": ""}`, http.StatusBadRequest, "", "", ""},
+		{"invalid date", `{"releaseDate": "2009-09-07"}`, http.StatusBadRequest, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+
+			var req types.PatchSongRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatalf("Unmarshal(%s) error = %v", tt.body, err)
+			}
+
+			song, err := s.PatchSong(context.Background(), 1, &req, types.WriteOptions{})
+			if got := statusCode(err); got != tt.wantStatus {
+				t.Fatalf("PatchSong() status = %d, want %d (error %v)", got, tt.wantStatus, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if song.Song != tt.wantSong || song.Group != tt.wantGroup || song.Text != tt.wantText {
+				t.Errorf("PatchSong() = %q by %q with text %q, want %q by %q with text %q",
+					song.Song, song.Group, song.Text, tt.wantSong, tt.wantGroup, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestAddSong(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        types.SongRequest
+		wantStatus int
+		wantSong   string
+		wantGroup  string
+	}{
+		{"ok", types.SongRequest{Song: "Hysteria", Group: "Muse"}, 0, "Hysteria", "Muse"},
+		{"normalized", types.SongRequest{Song: "  Hysteria ", Group: " The   Muse  "}, 0, "Hysteria", "The Muse"},
+		{"empty song", types.SongRequest{Song: "", Group: "Muse"}, http.StatusBadRequest, "", ""},
+		{"blank song", types.SongRequest{Song: " \t ", Group: "Muse"}, http.StatusBadRequest, "", ""},
+		{"empty group", types.SongRequest{Song: "Hysteria", Group: ""}, http.StatusBadRequest, "", ""},
+		{"blank group", types.SongRequest{Song: "Hysteria", Group: "   "}, http.StatusBadRequest, "", ""},
+		{"duplicate", types.SongRequest{Song: "uprising", Group: "muse"}, http.StatusConflict, "", ""},
+		{"same name in another group", types.SongRequest{Song: "Creep", Group: "Muse"}, 0, "Creep", "Muse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+
+			song, err := s.AddSong(context.Background(), &tt.req, types.WriteOptions{})
+			if got := statusCode(err); got != tt.wantStatus {
+				t.Fatalf("AddSong() status = %d, want %d (error %v)", got, tt.wantStatus, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if song.Song != tt.wantSong || song.Group != tt.wantGroup {
+				t.Errorf("AddSong() = %q by %q, want %q by %q", song.Song, song.Group, tt.wantSong, tt.wantGroup)
+			}
+			if song.EnrichmentStatus != types.EnrichmentPending {
+				t.Errorf("enrichment status = %q, want %q", song.EnrichmentStatus, types.EnrichmentPending)
+			}
+		})
+	}
+}
+
+func TestAddSongDuplicateID(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.AddSong(context.Background(), &types.SongRequest{Song: "UPRISING", Group: "Muse"}, types.WriteOptions{})
+
+	var apiErr errs.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Fatalf("AddSong() error = %v, want 409", err)
+	}
+	if apiErr.ExistingID != 1 {
+		t.Errorf("existing ID = %d, want 1", apiErr.ExistingID)
+	}
+}
+
+func TestUpdateSong(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        types.UpdateSongRequest
+		wantStatus int
+	}{
+		{"ok", types.UpdateSongRequest{Song: "Uprising", Group: "Muse", ReleaseDate: "07.09.2009"}, 0},
+		{"empty song", types.UpdateSongRequest{Song: " ", Group: "Muse", ReleaseDate: "07.09.2009"}, http.StatusBadRequest},
+		{"empty group", types.UpdateSongRequest{Song: "Uprising", Group: "", ReleaseDate: "07.09.2009"}, http.StatusBadRequest},
+		{"invalid date", types.UpdateSongRequest{Song: "Uprising", Group: "Muse", ReleaseDate: "2009-09-07"}, http.StatusBadRequest},
+		{"duplicate", types.UpdateSongRequest{Song: "Creep", Group: "Radiohead", ReleaseDate: "21.09.1992"}, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+
+			song, err := s.UpdateSong(context.Background(), 1, &tt.req, types.WriteOptions{})
+			if got := statusCode(err); got != tt.wantStatus {
+				t.Fatalf("UpdateSong() status = %d, want %d (error %v)", got, tt.wantStatus, err)
+			}
+			if err != nil {
+				return
+			}
+
+			// The updated song carries the version its ETag is made of.
+			if song.Version != 2 {
+				t.Errorf("UpdateSong() version = %d, want 2", song.Version)
+			}
+		})
+	}
+}
+
+func TestSongPreconditions(t *testing.T) {
+	writes := []struct {
+		name  string
+		write func(s *Service, opts types.WriteOptions) error
+	}{
+		{"update", func(s *Service, opts types.WriteOptions) error {
+			req := &types.UpdateSongRequest{Song: "Uprising", Group: "Muse", ReleaseDate: "07.09.2009"}
+			_, err := s.UpdateSong(context.Background(), 1, req, opts)
+			return err
+		}},
+		{"patch", func(s *Service, opts types.WriteOptions) error {
+			_, err := s.PatchSong(context.Background(), 1, patchText("They will not force us"), opts)
+			return err
+		}},
+		{"delete", func(s *Service, opts types.WriteOptions) error {
+			return s.DeleteSong(context.Background(), 1, opts)
+		}},
+	}
+
+	tests := []struct {
+		name       string
+		ifMatch    func(etag string) string
+		wantStatus int
+	}{
+		{"no If-Match", func(string) string { return "" }, 0},
+		{"current", func(etag string) string { return etag }, 0},
+		{"any", func(string) string { return "*" }, 0},
+		{"one of a list", func(etag string) string { return `"stale", ` + etag }, 0},
+		{"stale", func(string) string { return `"stale"` }, http.StatusPreconditionFailed},
+		{"weak", func(etag string) string { return "W/" + etag }, http.StatusPreconditionFailed},
+	}
+
+	for _, w := range writes {
+		for _, tt := range tests {
+			t.Run(w.name+"/"+tt.name, func(t *testing.T) {
+				s := newTestService(t)
+
+				song, err := s.GetSong(context.Background(), 1)
+				if err != nil {
+					t.Fatalf("GetSong() error = %v", err)
+				}
+
+				opts := types.WriteOptions{IfMatch: tt.ifMatch(lib.ETag(song))}
+
+				err = w.write(s, opts)
+				if got := statusCode(err); got != tt.wantStatus {
+					t.Fatalf("status = %d, want %d (error %v)", got, tt.wantStatus, err)
+				}
+			})
+		}
+	}
+}
+
+func TestSongPreconditionAfterChange(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	song, err := s.GetSong(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetSong() error = %v", err)
+	}
+	etag := lib.ETag(song)
+
+	if _, err := s.PatchSong(ctx, 1, patchText("first"), types.WriteOptions{IfMatch: etag}); err != nil {
+		t.Fatalf("PatchSong() error = %v", err)
+	}
+
+	// The ETag read before the first change is stale now.
+	_, err = s.PatchSong(ctx, 1, patchText("second"), types.WriteOptions{IfMatch: etag})
+	if got := statusCode(err); got != http.StatusPreconditionFailed {
+		t.Fatalf("PatchSong(stale) status = %d, want 412 (error %v)", got, err)
+	}
+
+	song, err = s.GetSong(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetSong() error = %v", err)
+	}
+	if song.Text != "first" || song.Version != 2 {
+		t.Errorf("song = %q at version %d, want %q at version 2", song.Text, song.Version, "first")
+	}
+}
+
+func TestSongNotFound(t *testing.T) {
+	const missing = 42
+
+	tests := []struct {
+		name string
+		call func(s *Service) error
+	}{
+		{"get", func(s *Service) error {
+			_, err := s.GetSong(context.Background(), missing)
+			return err
+		}},
+		{"text", func(s *Service) error {
+			_, err := s.GetSongText(context.Background(), types.Pagination{Page: 1, Size: 1}, missing)
+			return err
+		}},
+		{"update", func(s *Service) error {
+			req := &types.UpdateSongRequest{Song: "Nothing", Group: "Muse", ReleaseDate: "01.01.2000"}
+			_, err := s.UpdateSong(context.Background(), missing, req, types.WriteOptions{})
+			return err
+		}},
+		{"update with If-Match", func(s *Service) error {
+			req := &types.UpdateSongRequest{Song: "Nothing", Group: "Muse", ReleaseDate: "01.01.2000"}
+			_, err := s.UpdateSong(context.Background(), missing, req, types.WriteOptions{IfMatch: "*"})
+			return err
+		}},
+		{"patch", func(s *Service) error {
+			_, err := s.PatchSong(context.Background(), missing, patchText("nothing"), types.WriteOptions{})
+			return err
+		}},
+		{"delete", func(s *Service) error {
+			return s.DeleteSong(context.Background(), missing, types.WriteOptions{})
+		}},
+		{"restore", func(s *Service) error {
+			_, err := s.RestoreSong(context.Background(), missing, types.WriteOptions{})
+			return err
+		}},
+		{"revision", func(s *Service) error {
+			_, err := s.GetSongRevision(context.Background(), missing, 1)
+			return err
+		}},
+		{"restore revision", func(s *Service) error {
+			_, err := s.RestoreSongRevision(context.Background(), missing, 1, types.WriteOptions{})
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(newTestService(t))
+			if got := statusCode(err); got != http.StatusNotFound {
+				t.Fatalf("status = %d, want 404 (error %v)", got, err)
+			}
+		})
+	}
+}
+
+// stubDetails serves the details it holds by song name. The other songs
+// fail with err, or aren't found without one.
+func TestRestoreSongDuplicate(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	if err := s.DeleteSong(ctx, 1, types.WriteOptions{}); err != nil {
+		t.Fatalf("DeleteSong() error = %v", err)
+	}
+
+	if _, err := s.AddSong(ctx, &types.SongRequest{Song: "Uprising", Group: "Muse"}, types.WriteOptions{}); err != nil {
+		t.Fatalf("AddSong() error = %v", err)
+	}
+
+	_, err := s.RestoreSong(ctx, 1, types.WriteOptions{})
+	if got := statusCode(err); got != http.StatusConflict {
+		t.Fatalf("RestoreSong() status = %d, want 409 (error %v)", got, err)
+	}
+}
+
+type stubDetails struct {
+	details map[string]types.Song
+	err     error
+	// slow is a song whose lookup lasts until ctx is done.
+	slow string
+}
+
+func (p stubDetails) SongDetails(ctx context.Context, req *types.SongRequest) (*types.Song, error) {
+	if req.Song == p.slow {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if details, ok := p.details[req.Song]; ok {
+		return &details, nil
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return nil, metadata.ErrNotFound
+}
+
+func (p stubDetails) Health() []types.ProviderHealth {
+	return nil
+}
+
+func TestImportSongs(t *testing.T) {
+	hysteria := time.Date(2003, time.December, 1, 0, 0, 0, 0, time.UTC)
+	details := stubDetails{details: map[string]types.Song{
+		"Hysteria": {ReleaseDate: &hysteria, Text: "It's bugging me", Link: "https://example.com/hysteria"},
+	}}
+
+	rows := []types.ImportRow{
+		{Line: 2, Song: "Starlight", Group: "Muse", ReleaseDate: "04.09.2006"},
+		{Line: 3, Song: "Hysteria", Group: " Muse ", Link: "https://example.com/live"},
+		{Line: 4, Song: "Unknown", Group: "Muse"},
+		{Line: 5, Song: "", Group: "Muse", ReleaseDate: "01.12.2003"},
+		{Line: 6, Song: "Starlight", Group: "", ReleaseDate: "04.09.2006"},
+		{Line: 7, Song: "Starlight", Group: "Muse", ReleaseDate: "2006-09-04"},
+		{Line: 8, Song: strings.Repeat("a", maxFieldLength+1), Group: "Muse", ReleaseDate: "04.09.2006"},
+		{Line: 9, Err: errors.New("invalid JSON")},
+	}
+
+	s := newTestService(t)
+	s.details = details
+
+	report, err := s.ImportSongs(context.Background(), rows, "", types.WriteOptions{})
+	if err != nil {
+		t.Fatalf("ImportSongs() error = %v", err)
+	}
+
+	want := []types.ImportResult{
+		{Line: 2, ID: 3, Action: types.ImportAdded},
+		{Line: 3, ID: 4, Action: types.ImportAdded},
+		{Line: 4, Error: metadata.ErrNotFound.Error()},
+		{Line: 5, Error: errs.EmptySongName().Error()},
+		{Line: 6, Error: errs.EmptyGroupName().Error()},
+		{Line: 7, Error: errs.InvalidDate().Error()},
+		{Line: 8, Error: errs.FieldTooLong("song", maxFieldLength).Error()},
+		{Line: 9, Error: "invalid JSON"},
+	}
+
+	if len(report.Results) != len(want) {
+		t.Fatalf("results = %+v, want %+v", report.Results, want)
+	}
+	for i := range want {
+		if got := report.Results[i]; got != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+	if report.Imported != 2 || report.Failed != 6 {
+		t.Errorf("imported %d, failed %d, want 2 and 6", report.Imported, report.Failed)
+	}
+
+	// The enriched row keeps its own link.
+	song, err := s.GetSong(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("GetSong() error = %v", err)
+	}
+	if song.Group != "Muse" || song.Text != "It's bugging me" || song.Link != "https://example.com/live" {
+		t.Errorf("enriched song = %+v", song)
+	}
+	if song.ReleaseDate == nil || !song.ReleaseDate.Equal(hysteria) {
+		t.Errorf("release date = %v, want %v", song.ReleaseDate, hysteria)
+	}
+}
+
+func TestImportSongsTimeout(t *testing.T) {
+	hysteria := time.Date(2003, time.December, 1, 0, 0, 0, 0, time.UTC)
+
+	s := newTestService(t)
+	s.details = stubDetails{
+		details: map[string]types.Song{"Hysteria": {ReleaseDate: &hysteria}},
+		slow:    "Starlight",
+	}
+
+	rows := []types.ImportRow{
+		{Line: 2, Song: "Knights of Cydonia", Group: "Muse", ReleaseDate: "27.06.2006"},
+		{Line: 3, Song: "Hysteria", Group: "Muse"},
+		{Line: 4, Song: "Starlight", Group: "Muse"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	report, err := s.ImportSongs(ctx, rows, "", types.WriteOptions{})
+	if err != nil {
+		t.Fatalf("ImportSongs() error = %v", err)
+	}
+
+	// The rows that were ready are added, the one still waiting for its
+	// details fails.
+	want := []types.ImportResult{
+		{Line: 2, ID: 3, Action: types.ImportAdded},
+		{Line: 3, ID: 4, Action: types.ImportAdded},
+		{Line: 4, Error: errs.APICallTimeout().Error()},
+	}
+
+	if len(report.Results) != len(want) {
+		t.Fatalf("results = %+v, want %+v", report.Results, want)
+	}
+	for i := range want {
+		if got := report.Results[i]; got != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	if _, err := s.GetSong(context.Background(), 4); err != nil {
+		t.Errorf("GetSong(4) error = %v", err)
+	}
+}
+
+func TestImportSongsTracks(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	album, err := s.AddAlbum(ctx, &types.AlbumRequest{Title: "The Resistance", Group: "Muse", ReleaseDate: "14.09.2009"})
+	if err != nil {
+		t.Fatalf("AddAlbum() error = %v", err)
+	}
+
+	one, two, three := 1, 2, 3
+	missing := 42
+
+	if _, err := s.PatchSong(ctx, 1, &types.PatchSongRequest{
+		AlbumID:     types.Optional[int]{Set: true, Value: &album.ID},
+		TrackNumber: types.Optional[int]{Set: true, Value: &one},
+	}, types.WriteOptions{}); err != nil {
+		t.Fatalf("PatchSong() error = %v", err)
+	}
+
+	rows := []types.ImportRow{
+		{Line: 2, Song: "Uprising", Group: "Muse", ReleaseDate: "07.09.2009", AlbumID: &album.ID, TrackNumber: &one},
+		{Line: 3, Song: "Resistance", Group: "Muse", ReleaseDate: "14.09.2009", AlbumID: &album.ID, TrackNumber: &two},
+		{Line: 4, Song: "Undisclosed Desires", Group: "Muse", ReleaseDate: "14.09.2009", AlbumID: &album.ID, TrackNumber: &one},
+		{Line: 5, Song: "Unnatural Selection", Group: "Muse", ReleaseDate: "14.09.2009", AlbumID: &album.ID, TrackNumber: &two},
+		{Line: 6, Song: "MK Ultra", Group: "Muse", ReleaseDate: "14.09.2009", AlbumID: &missing},
+		{Line: 7, Song: "I Belong to You", Group: "Muse", ReleaseDate: "14.09.2009", TrackNumber: &three},
+	}
+
+	report, err := s.ImportSongs(ctx, rows, types.OnConflictUpdate, types.WriteOptions{})
+	if err != nil {
+		t.Fatalf("ImportSongs() error = %v", err)
+	}
+
+	// A song keeps its own track, the others can't take a track twice.
+	want := []types.ImportResult{
+		{Line: 2, ID: 1, Action: types.ImportUpdated},
+		{Line: 3, ID: 3, Action: types.ImportAdded},
+		{Line: 4, Error: errs.TrackNumberTaken().Error()},
+		{Line: 5, Error: errs.TrackNumberTaken().Error()},
+		{Line: 6, Error: errs.AlbumNotFound().Error()},
+		{Line: 7, Error: errs.InvalidTrackNumber().Error()},
+	}
+
+	if len(report.Results) != len(want) {
+		t.Fatalf("results = %+v, want %+v", report.Results, want)
+	}
+	for i := range want {
+		if got := report.Results[i]; got != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	song, err := s.GetSong(ctx, 3)
+	if err != nil {
+		t.Fatalf("GetSong() error = %v", err)
+	}
+	if song.AlbumID == nil || *song.AlbumID != album.ID || song.TrackNumber == nil || *song.TrackNumber != two {
+		t.Errorf("song 3 is track %v of album %v, want track %d of album %d", song.TrackNumber, song.AlbumID, two, album.ID)
+	}
+}
+
+func TestImportSongsOnConflict(t *testing.T) {
+	rows := []types.ImportRow{
+		{Line: 2, Song: "Hysteria", Group: "Muse", ReleaseDate: "01.12.2003"},
+		{Line: 3, Song: "UPRISING", Group: "Muse", ReleaseDate: "07.09.2009"},
+		{Line: 4, Song: "hysteria", Group: "Muse", ReleaseDate: "01.12.2003"},
+		{Line: 5, Song: "", Group: "Muse", ReleaseDate: "01.12.2003"},
+	}
+
+	tests := []struct {
+		onConflict string
+		wantStatus int
+		want       []types.ImportResult
+	}{
+		{"", 0, []types.ImportResult{
+			{Line: 2, ID: 3, Action: types.ImportAdded},
+			{Line: 3, Error: storage.DuplicateSongError{ID: 1}.Error()},
+			{Line: 4, Error: "song repeats line 2"},
+			{Line: 5, Error: errs.EmptySongName().Error()},
+		}},
+		{types.OnConflictSkip, 0, []types.ImportResult{
+			{Line: 2, ID: 3, Action: types.ImportAdded},
+			{Line: 3, ID: 1, Action: types.ImportSkipped},
+			{Line: 4, ID: 3, Action: types.ImportSkipped},
+			{Line: 5, Error: errs.EmptySongName().Error()},
+		}},
+		{types.OnConflictUpdate, 0, []types.ImportResult{
+			{Line: 2, ID: 3, Action: types.ImportAdded},
+			{Line: 3, ID: 1, Action: types.ImportUpdated},
+			{Line: 4, ID: 3, Action: types.ImportUpdated},
+			{Line: 5, Error: errs.EmptySongName().Error()},
+		}},
+		{"overwrite", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(cmp.Or(tt.onConflict, "default"), func(t *testing.T) {
+			s := newTestService(t)
+
+			report, err := s.ImportSongs(context.Background(), rows, tt.onConflict, types.WriteOptions{})
+			if got := statusCode(err); got != tt.wantStatus {
+				t.Fatalf("ImportSongs() status = %d, want %d (error %v)", got, tt.wantStatus, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if len(report.Results) != len(tt.want) {
+				t.Fatalf("results = %+v, want %+v", report.Results, tt.want)
+			}
+			for i, want := range tt.want {
+				if got := report.Results[i]; got != want {
+					t.Errorf("result %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunEnrichmentJob(t *testing.T) {
+	cfg := config.EnrichmentConfig{
+		EnrichmentMaxAttempts: 3,
+		EnrichmentRetryDelay:  time.Minute,
+	}
+
+	hysteria := time.Date(2003, time.December, 1, 0, 0, 0, 0, time.UTC)
+	details := stubDetails{
+		details: map[string]types.Song{"Hysteria": {ReleaseDate: &hysteria, Text: "It's bugging me"}},
+		err:     errors.New("details API is down"),
+	}
+
+	tests := []struct {
+		name       string
+		song       string
+		notFound   bool
+		attempts   int
+		wantStatus string
+		wantRetry  bool
+	}{
+		{"done", "Hysteria", false, 0, types.EnrichmentDone, false},
+		{"retried", "Unknown", false, 0, types.EnrichmentPending, true},
+		{"retried again", "Unknown", false, 1, types.EnrichmentPending, true},
+		{"failed after the last attempt", "Unknown", false, 2, types.EnrichmentFailed, false},
+		{"not found fails right away", "Unknown", true, 0, types.EnrichmentFailed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			s := newTestService(t)
+			s.details = details
+			if tt.notFound {
+				s.details = stubDetails{details: details.details}
+			}
+
+			if _, err := s.AddSong(ctx, &types.SongRequest{Song: tt.song, Group: "Muse"}, types.WriteOptions{}); err != nil {
+				t.Fatalf("AddSong() error = %v", err)
+			}
+
+			job, err := s.GetSongEnrichment(ctx, 3)
+			if err != nil {
+				t.Fatalf("GetSongEnrichment() error = %v", err)
+			}
+			if job.Status != types.EnrichmentPending {
+				t.Fatalf("status = %q, want %q", job.Status, types.EnrichmentPending)
+			}
+
+			// The job as claimed after earlier failed attempts.
+			job.Attempts = tt.attempts
+
+			s.runEnrichmentJob(ctx, job, cfg)
+
+			got, err := s.GetSongEnrichment(ctx, 3)
+			if err != nil {
+				t.Fatalf("GetSongEnrichment() error = %v", err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", got.Status, tt.wantStatus)
+			}
+			if (got.NextAttemptAt != nil) != tt.wantRetry {
+				t.Errorf("next attempt at %v, want retry %v", got.NextAttemptAt, tt.wantRetry)
+			}
+			if tt.wantStatus != types.EnrichmentDone && got.LastError == "" {
+				t.Error("last error is empty")
+			}
+
+			song, err := s.GetSong(ctx, 3)
+			if err != nil {
+				t.Fatalf("GetSong() error = %v", err)
+			}
+			if song.EnrichmentStatus != tt.wantStatus {
+				t.Errorf("song status = %q, want %q", song.EnrichmentStatus, tt.wantStatus)
+			}
+			if (song.ReleaseDate != nil) != (tt.wantStatus == types.EnrichmentDone) {
+				t.Errorf("release date = %v", song.ReleaseDate)
+			}
+		})
+	}
+}
+
+func TestRetrySongEnrichment(t *testing.T) {
+	releaseDate := time.Date(2009, time.September, 7, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		id         int
+		prepare    func(s *Service) error
+		wantStatus int
+	}{
+		{"pending", 1, func(s *Service) error { return nil }, 0},
+		{"failed", 1, func(s *Service) error {
+			return s.store.FailEnrichment(context.Background(), 1, "details API is down", nil)
+		}, 0},
+		{"done", 1, func(s *Service) error {
+			details := &types.Song{ReleaseDate: &releaseDate, Text: "Paranoia is in bloom"}
+			return s.store.CompleteEnrichment(context.Background(), 1, details, types.WriteOptions{})
+		}, http.StatusConflict},
+		{"missing", 42, func(s *Service) error { return nil }, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+
+			if err := tt.prepare(s); err != nil {
+				t.Fatalf("prepare error = %v", err)
+			}
+
+			job, err := s.RetrySongEnrichment(context.Background(), tt.id)
+			if got := statusCode(err); got != tt.wantStatus {
+				t.Fatalf("RetrySongEnrichment() status = %d, want %d (error %v)", got, tt.wantStatus, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if job.Status != types.EnrichmentPending || job.Attempts != 0 {
+				t.Errorf("job = %q after %d attempts, want %q after 0", job.Status, job.Attempts, types.EnrichmentPending)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{6, 32 * time.Minute},
+		{7, maxRetryDelay},
+		{100, maxRetryDelay},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(time.Minute, tt.attempt); got != tt.want {
+			t.Errorf("retryDelay(1m, %d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
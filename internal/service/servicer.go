@@ -7,9 +7,37 @@ import (
 )
 
 type Servicer interface {
-	GetSongs(context.Context, types.Pagination, types.Filter) ([]*types.Song, error)
+	GetSongs(context.Context, types.SongsQuery) (*types.SongsPage, error)
+	GetSong(context.Context, int) (*types.Song, error)
+	SearchSongs(context.Context, types.Pagination, string) ([]*types.SearchResult, error)
 	GetSongText(context.Context, types.Pagination, int) ([]string, error)
-	DeleteSong(context.Context, int) error
-	UpdateSong(context.Context, int, *types.UpdateSongRequest) error
-	AddSong(context.Context, *types.SongRequest) error
+	DeleteSong(context.Context, int, types.WriteOptions) error
+	UpdateSong(context.Context, int, *types.UpdateSongRequest, types.WriteOptions) (*types.Song, error)
+	PatchSong(context.Context, int, *types.PatchSongRequest, types.WriteOptions) (*types.Song, error)
+	AddSong(context.Context, *types.SongRequest, types.WriteOptions) (*types.Song, error)
+	ImportSongs(context.Context, []types.ImportRow, string, types.WriteOptions) (*types.ImportReport, error)
+	ExportSongs(context.Context, types.Filter, func(*types.Song) error) error
+	GetSongRevisions(context.Context, int, types.Pagination) ([]*types.Revision, error)
+	GetSongRevision(context.Context, int, int) (*types.Revision, error)
+	DiffSongRevisions(context.Context, int, int, int) (*types.RevisionDiff, error)
+	RestoreSongRevision(context.Context, int, int, types.WriteOptions) (*types.Song, error)
+	GetTrash(context.Context, types.Pagination) ([]*types.Song, error)
+	RestoreSong(context.Context, int, types.WriteOptions) (*types.Song, error)
+	GetSongEnrichment(context.Context, int) (*types.Enrichment, error)
+	GetEnrichmentJobs(context.Context, string, types.Pagination) ([]*types.Enrichment, error)
+	RetrySongEnrichment(context.Context, int) (*types.Enrichment, error)
+	GetHealth(context.Context) *types.Health
+	InvalidateDetailsCache(context.Context, string, string) (*types.CacheInvalidation, error)
+	GetGroups(context.Context, types.Pagination) ([]*types.Group, error)
+	GetGroup(context.Context, int) (*types.Group, error)
+	AddGroup(context.Context, *types.GroupRequest) (*types.Group, error)
+	UpdateGroup(context.Context, int, *types.GroupRequest) error
+	DeleteGroup(context.Context, int) error
+	GetGroupSongs(context.Context, int, types.SongsQuery) (*types.SongsPage, error)
+	GetAlbums(context.Context, types.Pagination) ([]*types.Album, error)
+	GetAlbum(context.Context, int) (*types.Album, error)
+	AddAlbum(context.Context, *types.AlbumRequest) (*types.Album, error)
+	UpdateAlbum(context.Context, int, *types.AlbumRequest) error
+	DeleteAlbum(context.Context, int) error
+	GetAlbumTracks(context.Context, int) ([]*types.Song, error)
 }
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/erknas/song-library/internal/errs"
+	"github.com/erknas/song-library/internal/logger"
+	"github.com/erknas/song-library/internal/logger/sl"
+	"github.com/erknas/song-library/internal/storage"
+	"github.com/erknas/song-library/internal/types"
+)
+
+const (
+	getTrashFn    = "GetTrash"
+	restoreSongFn = "RestoreSong"
+	purgeTrashFn  = "PurgeTrash"
+)
+
+func (s *Service) GetTrash(ctx context.Context, pag types.Pagination) ([]*types.Song, error) {
+	log := s.log.With(slog.String(fnName, getTrashFn))
+
+	log.DebugContext(ctx, "trash pagination", "pagination", pag)
+
+	songs, err := s.store.TrashedSongs(ctx, pag)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to get trashed songs", sl.Err(err))
+		return nil, err
+	}
+
+	if len(songs) == 0 {
+		log.InfoContext(ctx, "trash is empty")
+		return nil, errs.NoSongs()
+	}
+
+	log.InfoContext(ctx, "get trash OK")
+
+	return songs, nil
+}
+
+// RestoreSong takes song id out of the trash.
+func (s *Service) RestoreSong(ctx context.Context, id int, opts types.WriteOptions) (*types.Song, error) {
+	ctx = logger.WithSongID(ctx, id)
+	log := s.log.With(slog.String(fnName, restoreSongFn))
+
+	if err := s.store.UndeleteSong(ctx, id, opts); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.InfoContext(ctx, "song not found in trash")
+			return nil, errs.SongNotInTrash()
+		}
+		log.ErrorContext(ctx, "failed to restore song", sl.Err(err))
+		return nil, songError(err)
+	}
+
+	log.InfoContext(ctx, "restore song OK")
+
+	return s.GetSong(ctx, id)
+}
+
+// PurgeTrash deletes for good the songs that have been in the trash for
+// longer than retention.
+func (s *Service) PurgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	log := s.log.With(slog.String(fnName, purgeTrashFn))
+
+	purged, err := s.store.PurgeSongs(ctx, time.Now().Add(-retention))
+	if err != nil {
+		log.ErrorContext(ctx, "failed to purge trash", sl.Err(err))
+		return 0, err
+	}
+
+	log.InfoContext(ctx, "purge trash OK", "purged", purged)
+
+	return purged, nil
+}
+
+// RunTrashPurge calls PurgeTrash right away and then every interval until
+// ctx is done.
+func (s *Service) RunTrashPurge(ctx context.Context, interval, retention time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	s.PurgeTrash(ctx, retention)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.PurgeTrash(ctx, retention)
+		}
+	}
+}
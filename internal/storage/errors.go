@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrNotFound        = NotFoundError{}
+	ErrAlreadyExists   = errors.New("record already exists")
+	ErrInUse           = errors.New("record is still referenced")
+	ErrVersionMismatch = errors.New("record is at another version")
+	ErrDuplicateSong   = errors.New("song already exists")
+	ErrEnriched        = errors.New("song is already enriched")
+)
+
+// NotFoundError is returned when the requested record doesn't exist.
+// errors.Is(err, ErrNotFound) matches it whatever the resource.
+type NotFoundError struct {
+	Resource string
+	ID       int
+}
+
+func NotFound(resource string, id int) NotFoundError {
+	return NotFoundError{
+		Resource: resource,
+		ID:       id,
+	}
+}
+
+func (e NotFoundError) Error() string {
+	return fmt.Sprintf("%s %d not found", e.Resource, e.ID)
+}
+
+func (e NotFoundError) Is(target error) bool {
+	_, ok := target.(NotFoundError)
+	return ok
+}
+
+// DuplicateSongError is returned when a song would have the name and group
+// of the live song ID. errors.Is(err, ErrDuplicateSong) matches it.
+type DuplicateSongError struct {
+	ID int
+}
+
+func (e DuplicateSongError) Error() string {
+	return fmt.Sprintf("song already exists as %d", e.ID)
+}
+
+func (e DuplicateSongError) Is(target error) bool {
+	return target == ErrDuplicateSong
+}
+
+const (
+	songResource  = "song"
+	groupResource = "group"
+	albumResource = "album"
+
+	revisionResource = "revision"
+)
+
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+// uniqueSongIndex makes songs unique by normalized name within a group.
+const uniqueSongIndex = "idx_songs_unique_song"
+
+// rowError is pgError for statements addressing the single record id of
+// resource: a missing row becomes NotFoundError.
+func rowError(err error, resource string, id int) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return NotFound(resource, id)
+	}
+	return pgError(err)
+}
+
+// pgError translates Postgres errors that callers are expected to handle
+// into the storage sentinel errors. Other errors are returned as is.
+func pgError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolation:
+			if pgErr.ConstraintName == uniqueSongIndex {
+				return ErrDuplicateSong
+			}
+			return ErrAlreadyExists
+		case foreignKeyViolation:
+			return ErrInUse
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,590 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"iter"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/erknas/song-library/internal/types"
+)
+
+type MemoryStore struct {
+	mu             sync.RWMutex
+	songs          map[int]*types.Song
+	groups         map[int]*types.Group
+	albums         map[int]*types.Album
+	trash          map[int]*types.Song
+	revisions      map[int][]*types.Revision
+	enrichments    map[int]*types.Enrichment
+	nextID         int
+	nextGroupID    int
+	nextAlbumID    int
+	nextRevisionID int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		songs:          make(map[int]*types.Song),
+		groups:         make(map[int]*types.Group),
+		albums:         make(map[int]*types.Album),
+		trash:          make(map[int]*types.Song),
+		revisions:      make(map[int][]*types.Revision),
+		enrichments:    make(map[int]*types.Enrichment),
+		nextID:         1,
+		nextGroupID:    1,
+		nextAlbumID:    1,
+		nextRevisionID: 1,
+	}
+}
+
+func (m *MemoryStore) Songs(ctx context.Context, q types.SongsQuery) (*types.SongsPage, error) {
+	compare, err := songsCompare(q.SortKeys())
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var rows []rankedSong
+
+	for _, song := range m.filter(func(song *types.Song) bool { return matchFilter(song, q.Filter) }) {
+		row := rankedSong{song: song}
+		if q.Ranked() {
+			row.rank = fuzzyRank(song, q.Filter)
+		}
+		rows = append(rows, row)
+	}
+
+	total := len(rows)
+
+	if c := q.Pagination.Cursor; c != nil {
+		pos := rankedSong{
+			song: &types.Song{ID: c.ID, Song: c.Song, Group: c.Group, ReleaseDate: c.ReleaseDate},
+			rank: c.Rank,
+		}
+
+		after := rows[:0]
+		for _, row := range rows {
+			if compare(row, pos) > 0 {
+				after = append(after, row)
+			}
+		}
+		rows = after
+	}
+
+	sort.Slice(rows, func(i, j int) bool { return compare(rows[i], rows[j]) < 0 })
+
+	rows = paginate(rows, q.Pagination.Size+1, q.Pagination.Offset())
+
+	var (
+		songs = make([]*types.Song, len(rows))
+		ranks = make([]float64, len(rows))
+	)
+
+	for i, row := range rows {
+		songs[i], ranks[i] = row.song, row.rank
+	}
+
+	return newSongsPage(q, songs, ranks, total), nil
+}
+
+func (m *MemoryStore) SongByID(ctx context.Context, id int) (*types.Song, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	song, ok := m.songs[id]
+	if !ok {
+		return nil, NotFound(songResource, id)
+	}
+
+	cp := *song
+
+	return &cp, nil
+}
+
+// SearchSongs approximates the Postgres full-text search: a song matches
+// when its text contains every word of the query, and verses containing
+// any of the words are returned as highlighted snippets.
+func (m *MemoryStore) SearchSongs(ctx context.Context, q types.SearchQuery) ([]*types.SearchResult, error) {
+	terms := make(map[string]bool)
+	for _, w := range trgmWords(q.Query) {
+		terms[w] = true
+	}
+
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	type hit struct {
+		res  *types.SearchResult
+		rank int
+	}
+
+	var hits []hit
+
+	for _, song := range m.songs {
+		found := make(map[string]int)
+		for _, w := range trgmWords(song.Text) {
+			if terms[w] {
+				found[w]++
+			}
+		}
+
+		if len(found) < len(terms) {
+			continue
+		}
+
+		rank := 0
+		for _, n := range found {
+			rank += n
+		}
+
+		res := &types.SearchResult{
+			ID:          song.ID,
+			Song:        song.Song,
+			Group:       song.Group,
+			ReleaseDate: song.ReleaseDate,
+			Link:        song.Link,
+		}
+
+		for _, verse := range strings.Split(song.Text, "\n\n") {
+			if len(res.Snippets) == maxSnippets {
+				break
+			}
+			if snippet, ok := highlight(verse, terms); ok {
+				res.Snippets = append(res.Snippets, snippet)
+			}
+		}
+
+		hits = append(hits, hit{res: res, rank: rank})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].rank != hits[j].rank {
+			return hits[i].rank > hits[j].rank
+		}
+		return hits[i].res.ID < hits[j].res.ID
+	})
+
+	hits = paginate(hits, q.Pagination.Size, q.Pagination.Offset())
+
+	results := make([]*types.SearchResult, len(hits))
+	for i, h := range hits {
+		results[i] = h.res
+	}
+
+	return results, nil
+}
+
+func (m *MemoryStore) SongText(ctx context.Context, id int) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	song, ok := m.songs[id]
+	if !ok {
+		return "", NotFound(songResource, id)
+	}
+
+	return song.Text, nil
+}
+
+func (m *MemoryStore) DeleteSong(ctx context.Context, id int, opts types.WriteOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	song, err := m.songAt(id, opts)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	deleted := *song
+	deleted.Version++
+	deleted.DeletedAt = &now
+
+	delete(m.songs, id)
+	m.trash[id] = &deleted
+	m.recordRevision(&deleted, types.RevisionDelete, opts.Author)
+
+	return nil
+}
+
+func (m *MemoryStore) UpdateSong(ctx context.Context, id int, song *types.Song, opts types.WriteOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, err := m.songAt(id, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := m.checkTrack(id, song); err != nil {
+		return err
+	}
+
+	if err := m.checkDuplicate(id, song.Song, song.Group); err != nil {
+		return err
+	}
+
+	updated := *song
+	updated.ID = id
+	updated.Version = current.Version + 1
+	updated.EnrichmentStatus = current.EnrichmentStatus
+	updated.GroupID, updated.Group = m.upsertGroup(song.Group)
+	m.songs[id] = &updated
+	m.recordRevision(&updated, types.RevisionUpdate, opts.Author)
+
+	return nil
+}
+
+func (m *MemoryStore) PatchSong(ctx context.Context, id int, patch *types.SongPatch, opts types.WriteOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	song, err := m.songAt(id, opts)
+	if err != nil {
+		return err
+	}
+
+	patched := patch.Apply(song)
+	patched.Version++
+
+	if err := m.checkTrack(id, patched); err != nil {
+		return err
+	}
+
+	if err := m.checkDuplicate(id, patched.Song, patched.Group); err != nil {
+		return err
+	}
+
+	if patch.Group != nil {
+		patched.GroupID, patched.Group = m.upsertGroup(*patch.Group)
+	}
+	m.songs[id] = patched
+	m.recordRevision(patched, types.RevisionUpdate, opts.Author)
+
+	return nil
+}
+
+func (m *MemoryStore) AddSong(ctx context.Context, song *types.Song, opts types.WriteOptions) (*types.Song, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkTrack(0, song); err != nil {
+		return nil, err
+	}
+
+	if err := m.checkDuplicate(0, song.Song, song.Group); err != nil {
+		return nil, err
+	}
+
+	added := *song
+	added.ID = m.nextID
+	added.Version = 1
+	added.GroupID, added.Group = m.upsertGroup(song.Group)
+	m.songs[added.ID] = &added
+	m.nextID++
+	m.recordRevision(&added, types.RevisionAdd, opts.Author)
+
+	if added.EnrichmentStatus == types.EnrichmentPending {
+		now := time.Now()
+		m.enrichments[added.ID] = &types.Enrichment{NextAttemptAt: &now, UpdatedAt: &now}
+	}
+
+	stored := added
+
+	return &stored, nil
+}
+
+func (m *MemoryStore) Close() {}
+
+// storedSongs yields the songs including those in the trash, which still
+// refer to their group and album. The caller must hold m.mu.
+func (m *MemoryStore) storedSongs() iter.Seq[*types.Song] {
+	return func(yield func(*types.Song) bool) {
+		for _, songs := range []map[int]*types.Song{m.songs, m.trash} {
+			for _, song := range songs {
+				if !yield(song) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// songAt returns song id if it is at the version required by opts. The
+// caller must hold m.mu.
+func (m *MemoryStore) songAt(id int, opts types.WriteOptions) (*types.Song, error) {
+	song, ok := m.songs[id]
+	if !ok {
+		return nil, NotFound(songResource, id)
+	}
+
+	if opts.IfVersion != 0 && song.Version != opts.IfVersion {
+		return nil, ErrVersionMismatch
+	}
+
+	return song, nil
+}
+
+// checkDuplicate enforces the unique index on the normalized name of live
+// songs within a group for song id. The caller must hold m.mu.
+func (m *MemoryStore) checkDuplicate(id int, song, group string) error {
+	if dupID, ok := m.duplicateOf(id, song, group); ok {
+		return DuplicateSongError{ID: dupID}
+	}
+	return nil
+}
+
+// duplicateOf returns the live song other than id with the name and group.
+// The caller must hold m.mu.
+func (m *MemoryStore) duplicateOf(id int, song, group string) (int, bool) {
+	g := m.groupByName(group)
+	if g == nil {
+		return 0, false
+	}
+
+	key := normalizeSongName(song)
+
+	for _, other := range m.songs {
+		if other.ID != id && other.GroupID == g.ID && normalizeSongName(other.Song) == key {
+			return other.ID, true
+		}
+	}
+
+	return 0, false
+}
+
+// normalizeSongName is the memory counterpart of songNameKey.
+func normalizeSongName(song string) string {
+	return strings.ToLower(strings.Join(strings.Fields(song), " "))
+}
+
+// filter returns copies of the songs matching keep.
+// The caller must hold m.mu.
+func (m *MemoryStore) filter(keep func(*types.Song) bool) []*types.Song {
+	songs := make([]*types.Song, 0, len(m.songs))
+
+	for _, song := range m.songs {
+		if keep(song) {
+			cp := *song
+			songs = append(songs, &cp)
+		}
+	}
+
+	return songs
+}
+
+func matchFilter(song *types.Song, fil types.Filter) bool {
+	if fil.Song != "" && !matchText(song.Song, fil.Song, fil.Match) {
+		return false
+	}
+
+	if fil.Group != "" && !matchText(song.Group, fil.Group, fil.Match) {
+		return false
+	}
+
+	if fil.GroupID != 0 && song.GroupID != fil.GroupID {
+		return false
+	}
+
+	// Like NULL in SQL, a missing release date matches no date filter.
+	hasDate := fil.Date != nil || fil.DateFrom != nil || fil.DateTo != nil
+	if hasDate && song.ReleaseDate == nil {
+		return false
+	}
+
+	if fil.Date != nil && !sameDate(*song.ReleaseDate, *fil.Date) {
+		return false
+	}
+
+	if fil.DateFrom != nil && compareDates(*song.ReleaseDate, *fil.DateFrom) < 0 {
+		return false
+	}
+
+	if fil.DateTo != nil && compareDates(*song.ReleaseDate, *fil.DateTo) > 0 {
+		return false
+	}
+
+	return true
+}
+
+func matchText(value, query, match string) bool {
+	switch match {
+	case types.MatchPrefix:
+		return strings.HasPrefix(strings.ToLower(value), strings.ToLower(query))
+	case types.MatchFuzzy:
+		return strings.Contains(strings.ToLower(value), strings.ToLower(query)) ||
+			wordSimilarity(query, value) >= wordSimilarityThreshold
+	default:
+		return strings.EqualFold(value, query)
+	}
+}
+
+func fuzzyRank(song *types.Song, fil types.Filter) float64 {
+	var rank float64
+
+	if fil.Song != "" {
+		rank += wordSimilarity(fil.Song, song.Song)
+	}
+
+	if fil.Group != "" {
+		rank += wordSimilarity(fil.Group, song.Group)
+	}
+
+	return rank
+}
+
+type rankedSong struct {
+	song *types.Song
+	rank float64
+}
+
+// songsCompare mirrors orderBy: it compares songs key by key in the order
+// returned by SongsQuery.SortKeys.
+func songsCompare(keys []types.SortField) (func(a, b rankedSong) int, error) {
+	cmps := make([]func(a, b rankedSong) int, 0, len(keys))
+
+	for _, k := range keys {
+		var compare func(a, b rankedSong) int
+
+		if k.Field == types.SortByRank {
+			compare = func(a, b rankedSong) int { return cmp.Compare(a.rank, b.rank) }
+		} else {
+			songCompare, ok := songComparators[k.Field]
+			if !ok {
+				return nil, fmt.Errorf("unsupported sort field %q", k.Field)
+			}
+			compare = func(a, b rankedSong) int { return songCompare(a.song, b.song) }
+		}
+
+		if k.Desc {
+			asc := compare
+			compare = func(a, b rankedSong) int { return asc(b, a) }
+		}
+
+		if k.Field == types.SortByReleaseDate {
+			compare = undatedLast(compare)
+		}
+
+		cmps = append(cmps, compare)
+	}
+
+	return func(a, b rankedSong) int {
+		for _, compare := range cmps {
+			if c := compare(a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}, nil
+}
+
+var songComparators = map[string]func(a, b *types.Song) int{
+	types.SortByID: func(a, b *types.Song) int { return cmp.Compare(a.ID, b.ID) },
+	types.SortBySong: func(a, b *types.Song) int {
+		return strings.Compare(strings.ToLower(a.Song), strings.ToLower(b.Song))
+	},
+	types.SortByGroup: func(a, b *types.Song) int {
+		return strings.Compare(strings.ToLower(a.Group), strings.ToLower(b.Group))
+	},
+	// Missing release dates are handled by undatedLast.
+	types.SortByReleaseDate: func(a, b *types.Song) int { return compareDates(*a.ReleaseDate, *b.ReleaseDate) },
+}
+
+func paginate[T any](rows []T, size, offset int) []T {
+	if offset >= len(rows) {
+		return nil
+	}
+
+	rows = rows[offset:]
+
+	if size >= 0 && size < len(rows) {
+		rows = rows[:size]
+	}
+
+	return rows
+}
+
+// highlight wraps every word of verse found in terms with <b></b>, like
+// ts_headline does, and reports whether anything was highlighted.
+func highlight(verse string, terms map[string]bool) (string, bool) {
+	var (
+		b     strings.Builder
+		word  strings.Builder
+		found bool
+	)
+
+	flush := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := word.String()
+		if terms[strings.ToLower(w)] {
+			b.WriteString("<b>" + w + "</b>")
+			found = true
+		} else {
+			b.WriteString(w)
+		}
+		word.Reset()
+	}
+
+	for _, r := range verse {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word.WriteRune(r)
+			continue
+		}
+		flush()
+		b.WriteRune(r)
+	}
+	flush()
+
+	return b.String(), found
+}
+
+// undatedLast orders songs without a release date after all others
+// whatever the direction of compare, like the COALESCE to infinity or
+// -infinity the Postgres storage sorts by.
+func undatedLast(compare func(a, b rankedSong) int) func(a, b rankedSong) int {
+	return func(a, b rankedSong) int {
+		switch {
+		case a.song.ReleaseDate == nil && b.song.ReleaseDate == nil:
+			return 0
+		case a.song.ReleaseDate == nil:
+			return 1
+		case b.song.ReleaseDate == nil:
+			return -1
+		}
+		return compare(a, b)
+	}
+}
+
+func sameDate(a, b time.Time) bool {
+	return compareDates(a, b) == 0
+}
+
+// compareDates compares the calendar dates of a and b, ignoring the time of
+// day, the way Postgres compares DATE values.
+func compareDates(a, b time.Time) int {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+
+	if c := cmp.Compare(ay, by); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(am, bm); c != 0 {
+		return c
+	}
+	return cmp.Compare(ad, bd)
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"sort"
+
+	"github.com/erknas/song-library/internal/types"
+)
+
+func (m *MemoryStore) Albums(ctx context.Context, pag types.Pagination) ([]*types.Album, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	albums := make([]*types.Album, 0, len(m.albums))
+	for _, album := range m.albums {
+		albums = append(albums, m.albumCopy(album))
+	}
+
+	sort.Slice(albums, func(i, j int) bool {
+		if c := compareDates(albums[i].ReleaseDate, albums[j].ReleaseDate); c != 0 {
+			return c < 0
+		}
+		return cmp.Less(albums[i].ID, albums[j].ID)
+	})
+
+	return paginate(albums, pag.Size, pag.Offset()), nil
+}
+
+func (m *MemoryStore) Album(ctx context.Context, id int) (*types.Album, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	album, ok := m.albums[id]
+	if !ok {
+		return nil, NotFound(albumResource, id)
+	}
+
+	return m.albumCopy(album), nil
+}
+
+func (m *MemoryStore) AddAlbum(ctx context.Context, album *types.Album) (*types.Album, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	added := *album
+	added.ID = m.nextAlbumID
+	added.GroupID, _ = m.upsertGroup(album.Group)
+	m.albums[added.ID] = &added
+	m.nextAlbumID++
+
+	return m.albumCopy(&added), nil
+}
+
+func (m *MemoryStore) UpdateAlbum(ctx context.Context, id int, album *types.Album) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.albums[id]; !ok {
+		return NotFound(albumResource, id)
+	}
+
+	updated := *album
+	updated.ID = id
+	updated.GroupID, _ = m.upsertGroup(album.Group)
+	m.albums[id] = &updated
+
+	return nil
+}
+
+func (m *MemoryStore) DeleteAlbum(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.albums[id]; !ok {
+		return NotFound(albumResource, id)
+	}
+
+	for song := range m.storedSongs() {
+		if song.AlbumID != nil && *song.AlbumID == id {
+			return ErrInUse
+		}
+	}
+
+	delete(m.albums, id)
+
+	return nil
+}
+
+func (m *MemoryStore) AlbumTracks(ctx context.Context, id int) ([]*types.Song, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	songs := m.filter(func(song *types.Song) bool {
+		return song.AlbumID != nil && *song.AlbumID == id
+	})
+
+	// Tracks without a number go last, like NULLS LAST.
+	sort.Slice(songs, func(i, j int) bool {
+		a, b := songs[i].TrackNumber, songs[j].TrackNumber
+		switch {
+		case a != nil && b != nil && *a != *b:
+			return *a < *b
+		case (a == nil) != (b == nil):
+			return a != nil
+		}
+		return songs[i].ID < songs[j].ID
+	})
+
+	return songs, nil
+}
+
+// albumCopy returns a copy of album with the current name of its group.
+// The caller must hold m.mu.
+func (m *MemoryStore) albumCopy(album *types.Album) *types.Album {
+	cp := *album
+	if group, ok := m.groups[cp.GroupID]; ok {
+		cp.Group = group.Name
+	}
+	return &cp
+}
+
+// checkTrack enforces the foreign key to albums and the unique index on
+// (album_id, track_number) for song id. The caller must hold m.mu.
+func (m *MemoryStore) checkTrack(id int, song *types.Song) error {
+	if song.AlbumID == nil {
+		return nil
+	}
+
+	if _, ok := m.albums[*song.AlbumID]; !ok {
+		return ErrInUse
+	}
+
+	if song.TrackNumber == nil {
+		return nil
+	}
+
+	for _, other := range m.songs {
+		if other.ID != id && other.AlbumID != nil && *other.AlbumID == *song.AlbumID &&
+			other.TrackNumber != nil && *other.TrackNumber == *song.TrackNumber {
+			return ErrAlreadyExists
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/erknas/song-library/internal/types"
+)
+
+func (m *MemoryStore) SongEnrichment(ctx context.Context, id int) (*types.Enrichment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	song, ok := m.songs[id]
+	if !ok {
+		return nil, NotFound(songResource, id)
+	}
+
+	return m.enrichment(song), nil
+}
+
+func (m *MemoryStore) EnrichmentJobs(ctx context.Context, status string, pag types.Pagination) ([]*types.Enrichment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var jobs []*types.Enrichment
+
+	for _, song := range m.songs {
+		if song.EnrichmentStatus == types.EnrichmentDone || (status != "" && song.EnrichmentStatus != status) {
+			continue
+		}
+		jobs = append(jobs, m.enrichment(song))
+	}
+
+	slices.SortFunc(jobs, func(a, b *types.Enrichment) int { return cmp.Compare(a.SongID, b.SongID) })
+
+	return paginate(jobs, pag.Size, pag.Offset()), nil
+}
+
+func (m *MemoryStore) ClaimEnrichmentJobs(ctx context.Context, limit int, lease time.Duration) ([]*types.Enrichment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+
+	var due []*types.Song
+
+	for _, song := range m.songs {
+		state, ok := m.enrichments[song.ID]
+		if song.EnrichmentStatus != types.EnrichmentPending || !ok || state.NextAttemptAt.After(now) {
+			continue
+		}
+		due = append(due, song)
+	}
+
+	slices.SortFunc(due, func(a, b *types.Song) int {
+		return m.enrichments[a.ID].NextAttemptAt.Compare(*m.enrichments[b.ID].NextAttemptAt)
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	jobs := make([]*types.Enrichment, 0, len(due))
+	leased := now.Add(lease)
+
+	for _, song := range due {
+		m.enrichments[song.ID].NextAttemptAt = &leased
+		jobs = append(jobs, m.enrichment(song))
+	}
+
+	return jobs, nil
+}
+
+func (m *MemoryStore) CompleteEnrichment(ctx context.Context, id int, details *types.Song, opts types.WriteOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	song, ok := m.songs[id]
+	if !ok || song.EnrichmentStatus != types.EnrichmentPending {
+		return NotFound(songResource, id)
+	}
+
+	enriched := *song
+	if enriched.ReleaseDate == nil {
+		enriched.ReleaseDate = details.ReleaseDate
+	}
+	if enriched.Text == "" {
+		enriched.Text = details.Text
+	}
+	if enriched.Link == "" {
+		enriched.Link = details.Link
+	}
+	enriched.Version++
+	enriched.EnrichmentStatus = types.EnrichmentDone
+
+	m.songs[id] = &enriched
+	m.updateEnrichment(id, "", nil)
+	m.recordRevision(&enriched, types.RevisionUpdate, opts.Author)
+
+	return nil
+}
+
+func (m *MemoryStore) FailEnrichment(ctx context.Context, id int, msg string, retryAt *time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	song, ok := m.songs[id]
+	if !ok || song.EnrichmentStatus != types.EnrichmentPending {
+		return NotFound(songResource, id)
+	}
+
+	if retryAt == nil {
+		failed := *song
+		failed.EnrichmentStatus = types.EnrichmentFailed
+		m.songs[id] = &failed
+	}
+
+	m.updateEnrichment(id, msg, retryAt)
+
+	return nil
+}
+
+func (m *MemoryStore) RetryEnrichment(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	song, ok := m.songs[id]
+	if !ok {
+		return NotFound(songResource, id)
+	}
+	if song.EnrichmentStatus == types.EnrichmentDone {
+		return ErrEnriched
+	}
+
+	queued := *song
+	queued.EnrichmentStatus = types.EnrichmentPending
+	m.songs[id] = &queued
+
+	now := time.Now()
+	m.enrichments[id] = &types.Enrichment{NextAttemptAt: &now, UpdatedAt: &now}
+
+	return nil
+}
+
+// updateEnrichment counts an attempt at enriching song id. The caller must
+// hold m.mu.
+func (m *MemoryStore) updateEnrichment(id int, msg string, nextAt *time.Time) {
+	state, ok := m.enrichments[id]
+	if !ok {
+		state = new(types.Enrichment)
+		m.enrichments[id] = state
+	}
+
+	now := time.Now()
+	state.Attempts++
+	state.LastError = msg
+	state.NextAttemptAt = nextAt
+	state.UpdatedAt = &now
+}
+
+// enrichment describes the enrichment job of song. The caller must hold
+// m.mu.
+func (m *MemoryStore) enrichment(song *types.Song) *types.Enrichment {
+	job := &types.Enrichment{
+		SongID: song.ID,
+		Song:   song.Song,
+		Group:  song.Group,
+		Status: song.EnrichmentStatus,
+	}
+
+	if state, ok := m.enrichments[song.ID]; ok {
+		job.Attempts = state.Attempts
+		job.LastError = state.LastError
+		job.NextAttemptAt = state.NextAttemptAt
+		job.UpdatedAt = state.UpdatedAt
+	}
+
+	return job
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/erknas/song-library/internal/types"
+)
+
+// ExportSongs calls fn for a snapshot of the songs matching f in ID order.
+// The lock isn't held while fn runs, so a slow reader doesn't block writes.
+func (m *MemoryStore) ExportSongs(ctx context.Context, f types.Filter, fn func(*types.Song) error) error {
+	m.mu.RLock()
+	songs := m.filter(func(song *types.Song) bool { return matchFilter(song, f) })
+	m.mu.RUnlock()
+
+	slices.SortFunc(songs, func(a, b *types.Song) int { return cmp.Compare(a.ID, b.ID) })
+
+	for _, song := range songs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(song); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/erknas/song-library/internal/types"
+)
+
+func (m *MemoryStore) Groups(ctx context.Context, pag types.Pagination) ([]*types.Group, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	groups := make([]*types.Group, 0, len(m.groups))
+	for _, group := range m.groups {
+		cp := *group
+		groups = append(groups, &cp)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if c := strings.Compare(strings.ToLower(groups[i].Name), strings.ToLower(groups[j].Name)); c != 0 {
+			return c < 0
+		}
+		return cmp.Less(groups[i].ID, groups[j].ID)
+	})
+
+	return paginate(groups, pag.Size, pag.Offset()), nil
+}
+
+func (m *MemoryStore) Group(ctx context.Context, id int) (*types.Group, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	group, ok := m.groups[id]
+	if !ok {
+		return nil, NotFound(groupResource, id)
+	}
+
+	cp := *group
+
+	return &cp, nil
+}
+
+func (m *MemoryStore) AddGroup(ctx context.Context, name string) (*types.Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.groupByName(name) != nil {
+		return nil, ErrAlreadyExists
+	}
+
+	group := &types.Group{ID: m.nextGroupID, Name: name}
+	m.groups[group.ID] = group
+	m.nextGroupID++
+
+	cp := *group
+
+	return &cp, nil
+}
+
+func (m *MemoryStore) UpdateGroup(ctx context.Context, id int, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, ok := m.groups[id]
+	if !ok {
+		return NotFound(groupResource, id)
+	}
+
+	if other := m.groupByName(name); other != nil && other.ID != id {
+		return ErrAlreadyExists
+	}
+
+	group.Name = name
+
+	for song := range m.storedSongs() {
+		if song.GroupID == id {
+			song.Group = name
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) DeleteGroup(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.groups[id]; !ok {
+		return NotFound(groupResource, id)
+	}
+
+	for song := range m.storedSongs() {
+		if song.GroupID == id {
+			return ErrInUse
+		}
+	}
+
+	for _, album := range m.albums {
+		if album.GroupID == id {
+			return ErrInUse
+		}
+	}
+
+	delete(m.groups, id)
+
+	return nil
+}
+
+// groupByName finds a group the way the unique index on LOWER(name) does.
+// The caller must hold m.mu.
+func (m *MemoryStore) groupByName(name string) *types.Group {
+	for _, group := range m.groups {
+		if strings.EqualFold(group.Name, name) {
+			return group
+		}
+	}
+	return nil
+}
+
+// upsertGroup returns the ID and stored name of the group called name,
+// creating it on first use. The caller must hold m.mu for writing.
+func (m *MemoryStore) upsertGroup(name string) (int, string) {
+	if group := m.groupByName(name); group != nil {
+		return group.ID, group.Name
+	}
+
+	group := &types.Group{ID: m.nextGroupID, Name: name}
+	m.groups[group.ID] = group
+	m.nextGroupID++
+
+	return group.ID, group.Name
+}
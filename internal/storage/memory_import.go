@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/erknas/song-library/internal/types"
+)
+
+func (m *MemoryStore) ImportSongs(ctx context.Context, songs []*types.Song, onConflict string, opts types.WriteOptions) ([]types.ImportedSong, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	imported := make([]types.ImportedSong, 0, len(songs))
+
+	for _, song := range songs {
+		if id, ok := m.duplicateOf(0, song.Song, song.Group); ok {
+			if onConflict != types.OnConflictUpdate {
+				imported = append(imported, types.ImportedSong{ID: id, Action: types.ImportSkipped})
+				continue
+			}
+
+			current := m.songs[id]
+			updated := *current
+			updated.Song = song.Song
+			updated.ReleaseDate = song.ReleaseDate
+			updated.Text = song.Text
+			updated.Link = song.Link
+			if song.AlbumID != nil {
+				updated.AlbumID, updated.TrackNumber = song.AlbumID, song.TrackNumber
+			}
+			updated.Version++
+			updated.EnrichmentStatus = types.EnrichmentDone
+			m.songs[id] = &updated
+			if state, ok := m.enrichments[id]; ok {
+				now := time.Now()
+				state.LastError, state.NextAttemptAt, state.UpdatedAt = "", nil, &now
+			}
+			m.recordRevision(&updated, types.RevisionUpdate, opts.Author)
+
+			imported = append(imported, types.ImportedSong{ID: id, Action: types.ImportUpdated})
+			continue
+		}
+
+		added := *song
+		added.ID = m.nextID
+		added.Version = 1
+		added.EnrichmentStatus = types.EnrichmentDone
+		added.GroupID, added.Group = m.upsertGroup(song.Group)
+		m.songs[added.ID] = &added
+		m.nextID++
+		m.recordRevision(&added, types.RevisionAdd, opts.Author)
+
+		imported = append(imported, types.ImportedSong{ID: added.ID, Action: types.ImportAdded})
+	}
+
+	return imported, nil
+}
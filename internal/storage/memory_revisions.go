@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/erknas/song-library/internal/types"
+)
+
+func (m *MemoryStore) SongRevisions(ctx context.Context, id int, pag types.Pagination) ([]*types.Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	revisions := slices.Clone(m.revisions[id])
+	slices.Reverse(revisions)
+
+	return paginate(revisions, pag.Size, pag.Offset()), nil
+}
+
+func (m *MemoryStore) SongRevision(ctx context.Context, id, revID int) (*types.Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, rev := range m.revisions[id] {
+		if rev.ID == revID {
+			cp := *rev
+			return &cp, nil
+		}
+	}
+
+	return nil, NotFound(revisionResource, revID)
+}
+
+func (m *MemoryStore) RestoreSong(ctx context.Context, id int, song *types.Song, opts types.WriteOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var (
+		version = 0
+		status  = types.EnrichmentDone
+	)
+
+	if current, ok := m.songs[id]; ok {
+		if _, err := m.songAt(id, opts); err != nil {
+			return err
+		}
+		version, status = current.Version, current.EnrichmentStatus
+	} else if trashed, ok := m.trash[id]; ok {
+		if opts.IfVersion != 0 {
+			return NotFound(songResource, id)
+		}
+		version, status = trashed.Version, trashed.EnrichmentStatus
+	} else {
+		if opts.IfVersion != 0 {
+			return NotFound(songResource, id)
+		}
+		if revisions := m.revisions[id]; len(revisions) > 0 {
+			version = revisions[len(revisions)-1].Version
+		}
+	}
+
+	if err := m.checkTrack(id, song); err != nil {
+		return err
+	}
+
+	if err := m.checkDuplicate(id, song.Song, song.Group); err != nil {
+		return err
+	}
+
+	restored := *song
+	restored.ID = id
+	restored.Version = version + 1
+	restored.EnrichmentStatus = status
+	restored.GroupID, restored.Group = m.upsertGroup(song.Group)
+	delete(m.trash, id)
+	m.songs[id] = &restored
+	m.recordRevision(&restored, types.RevisionRestore, opts.Author)
+
+	return nil
+}
+
+// recordRevision appends a snapshot of song to its revisions. The caller
+// must hold m.mu for writing.
+func (m *MemoryStore) recordRevision(song *types.Song, action, author string) {
+	m.revisions[song.ID] = append(m.revisions[song.ID], &types.Revision{
+		ID:          m.nextRevisionID,
+		SongID:      song.ID,
+		Version:     song.Version,
+		Action:      action,
+		Author:      author,
+		CreatedAt:   time.Now(),
+		Song:        song.Song,
+		Group:       song.Group,
+		ReleaseDate: song.ReleaseDate,
+		Text:        song.Text,
+		Link:        song.Link,
+		AlbumID:     song.AlbumID,
+		TrackNumber: song.TrackNumber,
+	})
+	m.nextRevisionID++
+}
@@ -0,0 +1,499 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/erknas/song-library/internal/types"
+)
+
+func date(s string) *time.Time {
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return &d
+}
+
+// newTestStore returns a memory store holding the songs below with IDs 1
+// to 6, Muse being group 1 and Radiohead group 2.
+func newTestStore(t *testing.T) *MemoryStore {
+	t.Helper()
+
+	songs := []*types.Song{
+		{Song: "Supermassive Black Hole", Group: "Muse", ReleaseDate: date("2006-07-16")},
+		{Song: "Uprising", Group: "Muse", ReleaseDate: date("2009-09-07")},
+		{Song: "Starlight", Group: "Muse", ReleaseDate: date("2006-09-04")},
+		{Song: "Creep", Group: "Radiohead", ReleaseDate: date("1992-09-21")},
+		{Song: "Karma Police", Group: "Radiohead", ReleaseDate: date("1997-08-25")},
+		{Song: "New Song", Group: "Radiohead", EnrichmentStatus: types.EnrichmentPending},
+	}
+
+	m := NewMemoryStore()
+
+	for _, song := range songs {
+		if _, err := m.AddSong(context.Background(), song, types.WriteOptions{}); err != nil {
+			t.Fatalf("AddSong(%q) error = %v", song.Song, err)
+		}
+	}
+
+	return m
+}
+
+func songIDs(songs []*types.Song) []int {
+	ids := make([]int, len(songs))
+	for i, song := range songs {
+		ids[i] = song.ID
+	}
+	return ids
+}
+
+func TestMemoryStoreSongsFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter types.Filter
+		want   []int
+	}{
+		{"none", types.Filter{}, []int{1, 2, 3, 4, 5, 6}},
+		{"song ignores case", types.Filter{Song: "uprising"}, []int{2}},
+		{"exact song", types.Filter{Song: "Uprisin"}, nil},
+		{"group", types.Filter{Group: "Muse"}, []int{1, 2, 3}},
+		{"group ID", types.Filter{GroupID: 2}, []int{4, 5, 6}},
+		{"song and group", types.Filter{Song: "Creep", Group: "Muse"}, nil},
+		{"prefix", types.Filter{Song: "s", Match: types.MatchPrefix}, []int{1, 3}},
+		{"fuzzy contains", types.Filter{Song: "police", Match: types.MatchFuzzy}, []int{5}},
+		{"date", types.Filter{Date: date("2006-07-16")}, []int{1}},
+		{"date from", types.Filter{DateFrom: date("2006-09-04")}, []int{2, 3}},
+		{"date to", types.Filter{DateTo: date("1999-12-31")}, []int{4, 5}},
+		{"date range", types.Filter{DateFrom: date("1997-01-01"), DateTo: date("2006-12-31")}, []int{1, 3, 5}},
+	}
+
+	m := newTestStore(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := m.Songs(context.Background(), types.SongsQuery{
+				Filter:     tt.filter,
+				Sort:       []types.SortField{{Field: types.SortByID}},
+				Pagination: types.Pagination{Page: 1, Size: 10},
+			})
+			if err != nil {
+				t.Fatalf("Songs() error = %v", err)
+			}
+
+			if got := songIDs(page.Songs); !slices.Equal(got, tt.want) {
+				t.Errorf("Songs() = %v, want %v", got, tt.want)
+			}
+			if page.Total != len(tt.want) {
+				t.Errorf("Total = %d, want %d", page.Total, len(tt.want))
+			}
+		})
+	}
+}
+
+var sortTests = []struct {
+	name string
+	sort []types.SortField
+	want []int
+}{
+	{"default", nil, []int{1, 2, 3, 4, 5, 6}},
+	{"id desc", []types.SortField{{Field: types.SortByID, Desc: true}}, []int{6, 5, 4, 3, 2, 1}},
+	{"song", []types.SortField{{Field: types.SortBySong}}, []int{4, 5, 6, 3, 1, 2}},
+	{"song desc", []types.SortField{{Field: types.SortBySong, Desc: true}}, []int{2, 1, 3, 6, 5, 4}},
+	{"release date", []types.SortField{{Field: types.SortByReleaseDate}}, []int{4, 5, 1, 3, 2, 6}},
+	{"release date desc", []types.SortField{{Field: types.SortByReleaseDate, Desc: true}}, []int{2, 3, 1, 5, 4, 6}},
+	{"group then release date desc", []types.SortField{
+		{Field: types.SortByGroup},
+		{Field: types.SortByReleaseDate, Desc: true},
+	}, []int{2, 3, 1, 5, 4, 6}},
+	{"group desc", []types.SortField{{Field: types.SortByGroup, Desc: true}}, []int{4, 5, 6, 1, 2, 3}},
+}
+
+func TestMemoryStoreSongsSort(t *testing.T) {
+	m := newTestStore(t)
+
+	for _, tt := range sortTests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := m.Songs(context.Background(), types.SongsQuery{
+				Sort:       tt.sort,
+				Pagination: types.Pagination{Page: 1, Size: 10},
+			})
+			if err != nil {
+				t.Fatalf("Songs() error = %v", err)
+			}
+
+			if got := songIDs(page.Songs); !slices.Equal(got, tt.want) {
+				t.Errorf("Songs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreSongsPagination(t *testing.T) {
+	tests := []struct {
+		page, size int
+		want       []int
+		wantNext   bool
+	}{
+		{1, 4, []int{1, 2, 3, 4}, true},
+		{2, 4, []int{5, 6}, false},
+		{3, 4, nil, false},
+		{2, 2, []int{3, 4}, true},
+		{1, 6, []int{1, 2, 3, 4, 5, 6}, false},
+	}
+
+	m := newTestStore(t)
+
+	for _, tt := range tests {
+		page, err := m.Songs(context.Background(), types.SongsQuery{
+			Pagination: types.Pagination{Page: tt.page, Size: tt.size},
+		})
+		if err != nil {
+			t.Fatalf("Songs(page %d, size %d) error = %v", tt.page, tt.size, err)
+		}
+
+		if got := songIDs(page.Songs); !slices.Equal(got, tt.want) {
+			t.Errorf("Songs(page %d, size %d) = %v, want %v", tt.page, tt.size, got, tt.want)
+		}
+		if page.Total != 6 {
+			t.Errorf("Songs(page %d, size %d) total = %d, want 6", tt.page, tt.size, page.Total)
+		}
+		if (page.Next != nil) != tt.wantNext {
+			t.Errorf("Songs(page %d, size %d) next = %v, want next %v", tt.page, tt.size, page.Next, tt.wantNext)
+		}
+	}
+}
+
+func TestMemoryStoreSongsCursor(t *testing.T) {
+	m := newTestStore(t)
+
+	for _, tt := range sortTests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				got    []int
+				cursor *types.Cursor
+			)
+
+			for range len(tt.want) {
+				page, err := m.Songs(context.Background(), types.SongsQuery{
+					Sort:       tt.sort,
+					Pagination: types.Pagination{Page: 1, Size: 2, Cursor: cursor},
+				})
+				if err != nil {
+					t.Fatalf("Songs() error = %v", err)
+				}
+
+				got = append(got, songIDs(page.Songs)...)
+
+				if cursor = page.Next; cursor == nil {
+					break
+				}
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("pages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreSongsCursorStable(t *testing.T) {
+	m := newTestStore(t)
+
+	q := types.SongsQuery{
+		Sort:       []types.SortField{{Field: types.SortBySong}},
+		Pagination: types.Pagination{Page: 1, Size: 3},
+	}
+
+	first, err := m.Songs(context.Background(), q)
+	if err != nil {
+		t.Fatalf("Songs() error = %v", err)
+	}
+
+	// A song sorting before the cursor doesn't shift the next page.
+	if _, err := m.AddSong(context.Background(), &types.Song{Song: "Airbag", Group: "Radiohead"}, types.WriteOptions{}); err != nil {
+		t.Fatalf("AddSong() error = %v", err)
+	}
+
+	q.Pagination.Cursor = first.Next
+
+	second, err := m.Songs(context.Background(), q)
+	if err != nil {
+		t.Fatalf("Songs() error = %v", err)
+	}
+
+	if got, want := songIDs(second.Songs), []int{3, 1, 2}; !slices.Equal(got, want) {
+		t.Errorf("second page = %v, want %v", got, want)
+	}
+}
+
+func TestMemoryStoreDuplicates(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		write   func(m *MemoryStore) error
+		wantDup int
+	}{
+		{"add same name", func(m *MemoryStore) error {
+			_, err := m.AddSong(ctx, &types.Song{Song: "Uprising", Group: "Muse"}, types.WriteOptions{})
+			return err
+		}, 2},
+		{"add normalized name", func(m *MemoryStore) error {
+			_, err := m.AddSong(ctx, &types.Song{Song: "  supermassive   BLACK hole ", Group: "Muse"}, types.WriteOptions{})
+			return err
+		}, 1},
+		{"add in another group", func(m *MemoryStore) error {
+			_, err := m.AddSong(ctx, &types.Song{Song: "Uprising", Group: "Radiohead"}, types.WriteOptions{})
+			return err
+		}, 0},
+		{"update to another name", func(m *MemoryStore) error {
+			return m.UpdateSong(ctx, 2, &types.Song{Song: "Starlight", Group: "Muse"}, types.WriteOptions{})
+		}, 3},
+		{"update keeping the name", func(m *MemoryStore) error {
+			return m.UpdateSong(ctx, 2, &types.Song{Song: "UPRISING", Group: "Muse"}, types.WriteOptions{})
+		}, 0},
+		{"patch into another group", func(m *MemoryStore) error {
+			group := "Muse"
+			return m.PatchSong(ctx, 4, &types.SongPatch{Group: &group}, types.WriteOptions{})
+		}, 0},
+		{"patch to another name", func(m *MemoryStore) error {
+			song := "Creep"
+			return m.PatchSong(ctx, 5, &types.SongPatch{Song: &song}, types.WriteOptions{})
+		}, 4},
+		{"add a trashed song", func(m *MemoryStore) error {
+			if err := m.DeleteSong(ctx, 1, types.WriteOptions{}); err != nil {
+				return err
+			}
+			_, err := m.AddSong(ctx, &types.Song{Song: "Supermassive Black Hole", Group: "Muse"}, types.WriteOptions{})
+			return err
+		}, 0},
+		{"undelete over a new song", func(m *MemoryStore) error {
+			if err := m.DeleteSong(ctx, 1, types.WriteOptions{}); err != nil {
+				return err
+			}
+			if _, err := m.AddSong(ctx, &types.Song{Song: "Supermassive Black Hole", Group: "Muse"}, types.WriteOptions{}); err != nil {
+				return err
+			}
+			return m.UndeleteSong(ctx, 1, types.WriteOptions{})
+		}, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.write(newTestStore(t))
+
+			if tt.wantDup == 0 {
+				if err != nil {
+					t.Fatalf("error = %v, want nil", err)
+				}
+				return
+			}
+
+			var dup DuplicateSongError
+			if !errors.As(err, &dup) || !errors.Is(err, ErrDuplicateSong) {
+				t.Fatalf("error = %v, want DuplicateSongError", err)
+			}
+			if dup.ID != tt.wantDup {
+				t.Errorf("duplicate of %d, want %d", dup.ID, tt.wantDup)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreVersions(t *testing.T) {
+	ctx := context.Background()
+	m := newTestStore(t)
+
+	if err := m.UpdateSong(ctx, 1, &types.Song{Song: "Supermassive Black Hole", Group: "Muse"}, types.WriteOptions{IfVersion: 2}); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("UpdateSong(stale version) error = %v, want ErrVersionMismatch", err)
+	}
+
+	if err := m.UpdateSong(ctx, 1, &types.Song{Song: "Supermassive Black Hole", Group: "Muse"}, types.WriteOptions{IfVersion: 1}); err != nil {
+		t.Fatalf("UpdateSong() error = %v", err)
+	}
+
+	song, err := m.SongByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("SongByID() error = %v", err)
+	}
+	if song.Version != 2 {
+		t.Errorf("version = %d, want 2", song.Version)
+	}
+
+	if err := m.DeleteSong(ctx, 1, types.WriteOptions{IfVersion: 1}); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("DeleteSong(stale version) error = %v, want ErrVersionMismatch", err)
+	}
+
+	if err := m.UpdateSong(ctx, 42, &types.Song{Song: "Nothing", Group: "Muse"}, types.WriteOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateSong(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStoreTrash(t *testing.T) {
+	ctx := context.Background()
+	m := newTestStore(t)
+
+	if err := m.DeleteSong(ctx, 2, types.WriteOptions{}); err != nil {
+		t.Fatalf("DeleteSong() error = %v", err)
+	}
+
+	if _, err := m.SongByID(ctx, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SongByID(trashed) error = %v, want ErrNotFound", err)
+	}
+
+	if err := m.DeleteSong(ctx, 2, types.WriteOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteSong(trashed) error = %v, want ErrNotFound", err)
+	}
+
+	trash, err := m.TrashedSongs(ctx, types.Pagination{Page: 1, Size: 10})
+	if err != nil {
+		t.Fatalf("TrashedSongs() error = %v", err)
+	}
+	if len(trash) != 1 || trash[0].ID != 2 || trash[0].DeletedAt == nil || trash[0].Version != 2 {
+		t.Fatalf("TrashedSongs() = %+v, want song 2 deleted at version 2", trash)
+	}
+
+	if err := m.UndeleteSong(ctx, 2, types.WriteOptions{}); err != nil {
+		t.Fatalf("UndeleteSong() error = %v", err)
+	}
+
+	song, err := m.SongByID(ctx, 2)
+	if err != nil {
+		t.Fatalf("SongByID(restored) error = %v", err)
+	}
+	if song.DeletedAt != nil || song.Version != 3 {
+		t.Errorf("restored song = %+v, want live at version 3", song)
+	}
+
+	if err := m.UndeleteSong(ctx, 2, types.WriteOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UndeleteSong(live) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStorePurgeSongs(t *testing.T) {
+	ctx := context.Background()
+	m := newTestStore(t)
+
+	for _, id := range []int{1, 2} {
+		if err := m.DeleteSong(ctx, id, types.WriteOptions{}); err != nil {
+			t.Fatalf("DeleteSong(%d) error = %v", id, err)
+		}
+	}
+
+	purged, err := m.PurgeSongs(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeSongs(past) error = %v", err)
+	}
+	if purged != 0 {
+		t.Errorf("PurgeSongs(past) = %d, want 0", purged)
+	}
+
+	purged, err = m.PurgeSongs(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeSongs() error = %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("PurgeSongs() = %d, want 2", purged)
+	}
+
+	if err := m.UndeleteSong(ctx, 1, types.WriteOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UndeleteSong(purged) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStoreRevisions(t *testing.T) {
+	ctx := context.Background()
+	m := newTestStore(t)
+	opts := types.WriteOptions{Author: "tester"}
+
+	if err := m.UpdateSong(ctx, 4, &types.Song{Song: "Creep (Acoustic)", Group: "Radiohead", ReleaseDate: date("1993-01-01")}, opts); err != nil {
+		t.Fatalf("UpdateSong() error = %v", err)
+	}
+	if err := m.DeleteSong(ctx, 4, opts); err != nil {
+		t.Fatalf("DeleteSong() error = %v", err)
+	}
+
+	revisions, err := m.SongRevisions(ctx, 4, types.Pagination{Page: 1, Size: 10})
+	if err != nil {
+		t.Fatalf("SongRevisions() error = %v", err)
+	}
+
+	var actions []string
+	for _, rev := range revisions {
+		actions = append(actions, rev.Action)
+	}
+	if want := []string{types.RevisionDelete, types.RevisionUpdate, types.RevisionAdd}; !slices.Equal(actions, want) {
+		t.Fatalf("actions = %v, want %v", actions, want)
+	}
+	if revisions[1].Author != "tester" || revisions[1].Song != "Creep (Acoustic)" {
+		t.Errorf("update revision = %+v", revisions[1])
+	}
+
+	// Restoring the first revision brings the song out of the trash.
+	first := revisions[2]
+	restore := &types.Song{Song: first.Song, Group: first.Group, ReleaseDate: first.ReleaseDate}
+	if err := m.RestoreSong(ctx, 4, restore, opts); err != nil {
+		t.Fatalf("RestoreSong() error = %v", err)
+	}
+
+	song, err := m.SongByID(ctx, 4)
+	if err != nil {
+		t.Fatalf("SongByID() error = %v", err)
+	}
+	if song.Song != "Creep" || song.Version != 4 {
+		t.Errorf("restored song = %+v, want Creep at version 4", song)
+	}
+
+	if _, err := m.SongRevision(ctx, 4, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SongRevision(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStoreImportSongs(t *testing.T) {
+	tests := []struct {
+		onConflict string
+		want       []types.ImportedSong
+		wantSong   string
+	}{
+		{types.OnConflictSkip, []types.ImportedSong{
+			{ID: 7, Action: types.ImportAdded},
+			{ID: 2, Action: types.ImportSkipped},
+		}, "Uprising"},
+		{types.OnConflictUpdate, []types.ImportedSong{
+			{ID: 7, Action: types.ImportAdded},
+			{ID: 2, Action: types.ImportUpdated},
+		}, "UPRISING"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.onConflict, func(t *testing.T) {
+			ctx := context.Background()
+			m := newTestStore(t)
+
+			songs := []*types.Song{
+				{Song: "Hysteria", Group: "Muse", ReleaseDate: date("2003-12-01")},
+				{Song: "UPRISING", Group: "Muse", ReleaseDate: date("2009-08-03")},
+			}
+
+			got, err := m.ImportSongs(ctx, songs, tt.onConflict, types.WriteOptions{})
+			if err != nil {
+				t.Fatalf("ImportSongs() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ImportSongs() = %v, want %v", got, tt.want)
+			}
+
+			song, err := m.SongByID(ctx, 2)
+			if err != nil {
+				t.Fatalf("SongByID() error = %v", err)
+			}
+			if song.Song != tt.wantSong {
+				t.Errorf("song 2 = %q, want %q", song.Song, tt.wantSong)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/erknas/song-library/internal/types"
+)
+
+func (m *MemoryStore) TrashedSongs(ctx context.Context, pag types.Pagination) ([]*types.Song, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	songs := make([]*types.Song, 0, len(m.trash))
+	for _, song := range m.trash {
+		cp := *song
+		songs = append(songs, &cp)
+	}
+
+	slices.SortFunc(songs, func(a, b *types.Song) int {
+		if c := b.DeletedAt.Compare(*a.DeletedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return paginate(songs, pag.Size, pag.Offset()), nil
+}
+
+func (m *MemoryStore) UndeleteSong(ctx context.Context, id int, opts types.WriteOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	song, ok := m.trash[id]
+	if !ok {
+		return NotFound(songResource, id)
+	}
+
+	restored := *song
+	restored.Version++
+	restored.DeletedAt = nil
+
+	if err := m.checkTrack(id, &restored); err != nil {
+		return err
+	}
+
+	if err := m.checkDuplicate(id, restored.Song, restored.Group); err != nil {
+		return err
+	}
+
+	delete(m.trash, id)
+	m.songs[id] = &restored
+	m.recordRevision(&restored, types.RevisionRestore, opts.Author)
+
+	return nil
+}
+
+func (m *MemoryStore) PurgeSongs(ctx context.Context, before time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	purged := 0
+
+	for id, song := range m.trash {
+		if song.DeletedAt.Before(before) {
+			delete(m.trash, id)
+			delete(m.enrichments, id)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
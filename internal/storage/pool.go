@@ -1,4 +1,4 @@
-package lib
+package storage
 
 import (
 	"fmt"
@@ -7,7 +7,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func PoolConfig(cfg *config.Config) (*pgxpool.Config, error) {
+func poolConfig(cfg *config.Config) (*pgxpool.Config, error) {
 	dns := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DBName)
 	return pgxpool.ParseConfig(dns)
 }
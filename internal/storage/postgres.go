@@ -2,10 +2,10 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/erknas/song-library/internal/config"
-	"github.com/erknas/song-library/internal/lib"
 	"github.com/erknas/song-library/internal/types"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -13,15 +13,31 @@ import (
 
 const ctxTimeout time.Duration = time.Second * 5
 
+// upsertGroup resolves @group_name to the ID of its group, creating the
+// group on first use. Statements that need the ID select it from grp.
+const upsertGroup = `WITH grp AS (
+	INSERT INTO groups(name) VALUES(@group_name)
+	ON CONFLICT ((LOWER(name))) DO UPDATE SET name=groups.name
+	RETURNING id
+)`
+
+// maxSnippets limits how many highlighted verses a search result carries.
+const maxSnippets = 3
+
 type PostgresPool struct {
 	pool *pgxpool.Pool
 }
 
+// rowQuerier is a pool or a transaction.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 func NewPostgresPool(ctx context.Context, cfg *config.Config) (*PostgresPool, error) {
 	ctx, cancel := context.WithTimeout(ctx, ctxTimeout)
 	defer cancel()
 
-	poolCfg, err := lib.PoolConfig(cfg)
+	poolCfg, err := poolConfig(cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -38,16 +54,10 @@ func NewPostgresPool(ctx context.Context, cfg *config.Config) (*PostgresPool, er
 	return &PostgresPool{pool: pool}, nil
 }
 
-func (p *PostgresPool) Songs(ctx context.Context, pag types.Pagination) ([]*types.Song, error) {
-	query := `SELECT id, song, group_name, release_date, text, link 
-		  	  FROM songs
-			  ORDER BY id 
-			  LIMIT @size 
-			  OFFSET @page
-			 `
-	args := pgx.NamedArgs{
-		"size": pag.Size,
-		"page": pag.Page,
+func (p *PostgresPool) Songs(ctx context.Context, q types.SongsQuery) (*types.SongsPage, error) {
+	query, args, err := songsQuery(q)
+	if err != nil {
+		return nil, err
 	}
 
 	rows, err := p.pool.Query(ctx, query, args)
@@ -56,51 +66,108 @@ func (p *PostgresPool) Songs(ctx context.Context, pag types.Pagination) ([]*type
 	}
 	defer rows.Close()
 
-	var songs []*types.Song
+	var (
+		songs []*types.Song
+		ranks []float64
+		total int
+	)
 
 	for rows.Next() {
-		song := new(types.Song)
-		if err := rows.Scan(&song.ID, &song.Song, &song.Group, &song.ReleaseDate, &song.Text, &song.Link); err != nil {
+		var (
+			song = new(types.Song)
+			rank float64
+		)
+		if err := rows.Scan(&song.ID, &song.Song, &song.GroupID, &song.Group, &song.ReleaseDate, &song.Text, &song.Link, &song.AlbumID, &song.TrackNumber, &song.Version, &song.EnrichmentStatus, &rank, &total); err != nil {
 			return nil, err
 		}
 		songs = append(songs, song)
+		ranks = append(ranks, rank)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return songs, nil
+	return newSongsPage(q, songs, ranks, total), nil
+}
+
+func (p *PostgresPool) SongByID(ctx context.Context, id int) (*types.Song, error) {
+	return songByID(ctx, p.pool, id)
+}
+
+func songByID(ctx context.Context, q rowQuerier, id int) (*types.Song, error) {
+	query := `SELECT s.id, s.song, s.group_id, g.name, s.release_date, s.text, s.link, s.album_id, s.track_number, s.version, s.enrichment_status
+			  FROM songs s
+			  JOIN groups g ON g.id = s.group_id
+			  WHERE s.id=@id AND s.deleted_at IS NULL
+			 `
+
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	song := new(types.Song)
+
+	row := q.QueryRow(ctx, query, args)
+
+	if err := row.Scan(&song.ID, &song.Song, &song.GroupID, &song.Group, &song.ReleaseDate, &song.Text, &song.Link, &song.AlbumID, &song.TrackNumber, &song.Version, &song.EnrichmentStatus); err != nil {
+		return nil, rowError(err, songResource, id)
+	}
+
+	return song, nil
 }
 
-func (p *PostgresPool) SongsByFilters(ctx context.Context, query string, args []any) ([]*types.Song, error) {
-	rows, err := p.pool.Query(ctx, query, args...)
+func (p *PostgresPool) SearchSongs(ctx context.Context, q types.SearchQuery) ([]*types.SearchResult, error) {
+	query := `SELECT s.id, s.song, g.name, s.release_date, s.link,
+			  ARRAY(
+				SELECT ts_headline('simple', v, tsq, 'HighlightAll=true, StartSel=<b>, StopSel=</b>')
+				FROM unnest(string_to_array(s.text, E'\n\n')) AS v
+				WHERE to_tsvector('simple', v) @@ tsq
+				LIMIT @snippets
+			  )
+			  FROM songs s
+			  JOIN groups g ON g.id = s.group_id
+			  CROSS JOIN websearch_to_tsquery('simple', @query) tsq
+			  WHERE s.text_search @@ tsq AND s.deleted_at IS NULL
+			  ORDER BY ts_rank(s.text_search, tsq) DESC, s.id
+			  LIMIT @size
+			  OFFSET @offset
+			 `
+
+	args := pgx.NamedArgs{
+		"query":    q.Query,
+		"snippets": maxSnippets,
+		"size":     q.Pagination.Size,
+		"offset":   q.Pagination.Offset(),
+	}
+
+	rows, err := p.pool.Query(ctx, query, args)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var songs []*types.Song
+	var results []*types.SearchResult
 
 	for rows.Next() {
-		song := new(types.Song)
-		if err := rows.Scan(&song.ID, &song.Song, &song.Group, &song.ReleaseDate, &song.Text, &song.Link); err != nil {
+		res := new(types.SearchResult)
+		if err := rows.Scan(&res.ID, &res.Song, &res.Group, &res.ReleaseDate, &res.Link, &res.Snippets); err != nil {
 			return nil, err
 		}
-		songs = append(songs, song)
+		results = append(results, res)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return songs, nil
+	return results, nil
 }
 
 func (p *PostgresPool) SongText(ctx context.Context, id int) (string, error) {
 	query := `SELECT text 
 			  FROM songs 
-			  WHERE id=@id
+			  WHERE id=@id AND deleted_at IS NULL
 			 `
 
 	args := pgx.NamedArgs{
@@ -112,66 +179,174 @@ func (p *PostgresPool) SongText(ctx context.Context, id int) (string, error) {
 	var text string
 
 	if err := row.Scan(&text); err != nil {
-		return text, err
+		return text, rowError(err, songResource, id)
 	}
 
 	return text, nil
 }
 
-func (p *PostgresPool) DeleteSong(ctx context.Context, id int) error {
-	query := `DELETE FROM songs 
-			  WHERE id=@id
+// DeleteSong moves song id to the trash.
+func (p *PostgresPool) DeleteSong(ctx context.Context, id int, opts types.WriteOptions) error {
+	query := `UPDATE songs
+			  SET deleted_at=now(), version=version+1
+			  WHERE id=@id AND deleted_at IS NULL` + ifVersion + `
 			 `
 
 	args := pgx.NamedArgs{
-		"id": id,
+		"id":         id,
+		"if_version": opts.IfVersion,
+	}
+
+	return p.changeSong(ctx, id, query, args, types.RevisionDelete, opts)
+}
+
+func (p *PostgresPool) UpdateSong(ctx context.Context, id int, song *types.Song, opts types.WriteOptions) error {
+	query, args := updateSongQuery(id, song, opts)
+
+	err := p.changeSong(ctx, id, query, args, types.RevisionUpdate, opts)
+
+	return p.duplicateSong(ctx, err, id, song.Song, song.Group)
+}
+
+func (p *PostgresPool) PatchSong(ctx context.Context, id int, patch *types.SongPatch, opts types.WriteOptions) error {
+	query, args := patchSongQuery(id, patch, opts)
+
+	err := p.changeSong(ctx, id, query, args, types.RevisionUpdate, opts)
+	if !errors.Is(err, ErrDuplicateSong) {
+		return err
+	}
+
+	current, lookupErr := p.SongByID(ctx, id)
+	if lookupErr != nil {
+		return err
 	}
+	patched := patch.Apply(current)
+
+	return p.duplicateSong(ctx, err, id, patched.Song, patched.Group)
+}
+
+// changeSong runs the UPDATE of song id in query and records the new state
+// of the song as a revision.
+func (p *PostgresPool) changeSong(ctx context.Context, id int, query string, args pgx.NamedArgs, action string, opts types.WriteOptions) error {
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, args)
+		if err != nil {
+			return pgError(err)
+		}
 
-	_, err := p.pool.Exec(ctx, query, args)
+		if tag.RowsAffected() == 0 {
+			return songUnchanged(ctx, tx, id)
+		}
 
-	return err
+		return recordRevision(ctx, tx, id, action, opts.Author)
+	})
 }
 
-func (p *PostgresPool) UpdateSong(ctx context.Context, id int, song *types.Song) error {
-	query := `UPDATE songs 
-			  SET
-			  song=@song,
-			  group_name=@group_name, 
-			  release_date=@release_date, 
-			  text=@text, 
-			  link=@link
-			  WHERE id=@id
+// songUnchanged explains why a conditional change of song id affected no
+// rows: either the song doesn't exist or it is at another version.
+func songUnchanged(ctx context.Context, tx pgx.Tx, id int) error {
+	query := `SELECT 1
+			  FROM songs
+			  WHERE id=@id AND deleted_at IS NULL
 			 `
 
 	args := pgx.NamedArgs{
-		"song":         song.Song,
-		"group_name":   song.Group,
-		"release_date": song.ReleaseDate,
-		"text":         song.Text,
-		"link":         song.Link,
-		"id":           id,
+		"id": id,
+	}
+
+	var one int
+
+	if err := tx.QueryRow(ctx, query, args).Scan(&one); err != nil {
+		return rowError(err, songResource, id)
+	}
+
+	return ErrVersionMismatch
+}
+
+// AddSong stores song and returns it as stored, with its ID and version.
+func (p *PostgresPool) AddSong(ctx context.Context, song *types.Song, opts types.WriteOptions) (*types.Song, error) {
+	query := upsertGroup + `
+			  INSERT INTO songs(song, group_id, release_date, text, link, album_id, track_number,
+			  enrichment_status, enrichment_next_at, enrichment_updated_at)
+			  SELECT @song, grp.id, @release_date, @text, @link, @album_id, @track_number,
+			  @enrichment_status, CASE WHEN @enrichment_status = 'pending' THEN now() END, now()
+			  FROM grp
+			  RETURNING id
+			 `
+	args := pgx.NamedArgs{
+		"song":              song.Song,
+		"group_name":        song.Group,
+		"release_date":      song.ReleaseDate,
+		"text":              song.Text,
+		"link":              song.Link,
+		"album_id":          song.AlbumID,
+		"track_number":      song.TrackNumber,
+		"enrichment_status": song.EnrichmentStatus,
+	}
+
+	var added *types.Song
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		var id int
+
+		if err := tx.QueryRow(ctx, query, args).Scan(&id); err != nil {
+			return pgError(err)
+		}
+
+		if err := recordRevision(ctx, tx, id, types.RevisionAdd, opts.Author); err != nil {
+			return err
+		}
+
+		var err error
+		added, err = songByID(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, p.duplicateSong(ctx, err, 0, song.Song, song.Group)
 	}
 
-	_, err := p.pool.Exec(ctx, query, args)
+	return added, nil
+}
 
-	return err
+// songNameKey normalizes the song name in expr the way idx_songs_unique_song
+// does.
+func songNameKey(expr string) string {
+	return `LOWER(regexp_replace(btrim(` + expr + `), '\s+', ' ', 'g'))`
 }
 
-func (p *PostgresPool) AddSong(ctx context.Context, song *types.Song) error {
-	query := `INSERT INTO songs(song, group_name, release_date, text, link)
-			  VALUES(@song, @group_name, @release_date, @text, @link)
+// duplicateSong turns ErrDuplicateSong from writing song id as song from
+// group into a DuplicateSongError naming the live song it clashes with.
+// Other errors are returned as is.
+func (p *PostgresPool) duplicateSong(ctx context.Context, err error, id int, song, group string) error {
+	if !errors.Is(err, ErrDuplicateSong) {
+		return err
+	}
+
+	query := `SELECT s.id
+			  FROM songs s
+			  JOIN groups g ON g.id = s.group_id
+			  WHERE ` + songNameKey("s.song") + ` = ` + songNameKey("@song") + `
+			  AND LOWER(g.name) = LOWER(@group_name)
+			  AND s.deleted_at IS NULL AND s.id <> @id
 			 `
+
 	args := pgx.NamedArgs{
-		"song":         song.Song,
-		"group_name":   song.Group,
-		"release_date": song.ReleaseDate,
-		"text":         song.Text,
-		"link":         song.Link,
+		"id":         id,
+		"song":       song,
+		"group_name": group,
 	}
 
-	_, err := p.pool.Exec(ctx, query, args)
+	var dupID int
+
+	if err := p.pool.QueryRow(ctx, query, args).Scan(&dupID); err != nil {
+		// The other song is gone already.
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrDuplicateSong
+		}
+		return err
+	}
 
-	return err
+	return DuplicateSongError{ID: dupID}
 }
 
 func (p *PostgresPool) Close() {
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/erknas/song-library/internal/types"
+	"github.com/jackc/pgx/v5"
+)
+
+func (p *PostgresPool) Albums(ctx context.Context, pag types.Pagination) ([]*types.Album, error) {
+	query := `SELECT a.id, a.title, a.group_id, g.name, a.release_date, a.cover_link
+			  FROM albums a
+			  JOIN groups g ON g.id = a.group_id
+			  ORDER BY a.release_date, a.id
+			  LIMIT @size
+			  OFFSET @offset
+			 `
+
+	args := pgx.NamedArgs{
+		"size":   pag.Size,
+		"offset": pag.Offset(),
+	}
+
+	rows, err := p.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var albums []*types.Album
+
+	for rows.Next() {
+		album := new(types.Album)
+		if err := rows.Scan(&album.ID, &album.Title, &album.GroupID, &album.Group, &album.ReleaseDate, &album.CoverLink); err != nil {
+			return nil, err
+		}
+		albums = append(albums, album)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return albums, nil
+}
+
+func (p *PostgresPool) Album(ctx context.Context, id int) (*types.Album, error) {
+	query := `SELECT a.id, a.title, a.group_id, g.name, a.release_date, a.cover_link
+			  FROM albums a
+			  JOIN groups g ON g.id = a.group_id
+			  WHERE a.id=@id
+			 `
+
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	album := new(types.Album)
+
+	row := p.pool.QueryRow(ctx, query, args)
+
+	if err := row.Scan(&album.ID, &album.Title, &album.GroupID, &album.Group, &album.ReleaseDate, &album.CoverLink); err != nil {
+		return nil, rowError(err, albumResource, id)
+	}
+
+	return album, nil
+}
+
+func (p *PostgresPool) AddAlbum(ctx context.Context, album *types.Album) (*types.Album, error) {
+	query := upsertGroup + `
+			  INSERT INTO albums(title, group_id, release_date, cover_link)
+			  SELECT @title, grp.id, @release_date, @cover_link
+			  FROM grp
+			  RETURNING id, group_id
+			 `
+
+	args := pgx.NamedArgs{
+		"title":        album.Title,
+		"group_name":   album.Group,
+		"release_date": album.ReleaseDate,
+		"cover_link":   album.CoverLink,
+	}
+
+	added := *album
+
+	if err := p.pool.QueryRow(ctx, query, args).Scan(&added.ID, &added.GroupID); err != nil {
+		return nil, pgError(err)
+	}
+
+	return &added, nil
+}
+
+func (p *PostgresPool) UpdateAlbum(ctx context.Context, id int, album *types.Album) error {
+	query := upsertGroup + `
+			  UPDATE albums
+			  SET
+			  title=@title,
+			  group_id=(SELECT id FROM grp),
+			  release_date=@release_date,
+			  cover_link=@cover_link
+			  WHERE id=@id
+			 `
+
+	args := pgx.NamedArgs{
+		"title":        album.Title,
+		"group_name":   album.Group,
+		"release_date": album.ReleaseDate,
+		"cover_link":   album.CoverLink,
+		"id":           id,
+	}
+
+	tag, err := p.pool.Exec(ctx, query, args)
+	if err != nil {
+		return pgError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return NotFound(albumResource, id)
+	}
+
+	return nil
+}
+
+func (p *PostgresPool) DeleteAlbum(ctx context.Context, id int) error {
+	query := `DELETE FROM albums
+			  WHERE id=@id
+			 `
+
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	tag, err := p.pool.Exec(ctx, query, args)
+	if err != nil {
+		return pgError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return NotFound(albumResource, id)
+	}
+
+	return nil
+}
+
+func (p *PostgresPool) AlbumTracks(ctx context.Context, id int) ([]*types.Song, error) {
+	query := `SELECT s.id, s.song, s.group_id, g.name, s.release_date, s.text, s.link, s.album_id, s.track_number, s.version, s.enrichment_status
+			  FROM songs s
+			  JOIN groups g ON g.id = s.group_id
+			  WHERE s.album_id=@id AND s.deleted_at IS NULL
+			  ORDER BY s.track_number NULLS LAST, s.id
+			 `
+
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	rows, err := p.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var songs []*types.Song
+
+	for rows.Next() {
+		song := new(types.Song)
+		if err := rows.Scan(&song.ID, &song.Song, &song.GroupID, &song.Group, &song.ReleaseDate, &song.Text, &song.Link, &song.AlbumID, &song.TrackNumber, &song.Version, &song.EnrichmentStatus); err != nil {
+			return nil, err
+		}
+		songs = append(songs, song)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return songs, nil
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/erknas/song-library/internal/types"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresDetailsCache keeps details lookups in the details_cache table, so
+// they are shared by every instance and survive restarts. It holds at most
+// size entries; the least recently used ones are deleted to make room.
+// Expired entries are never returned and are overwritten by the next lookup
+// of their key or aged out like the others.
+type PostgresDetailsCache struct {
+	pool *pgxpool.Pool
+	size int
+}
+
+func (p *PostgresPool) DetailsCache(size int) *PostgresDetailsCache {
+	return &PostgresDetailsCache{pool: p.pool, size: size}
+}
+
+func (c *PostgresDetailsCache) Get(ctx context.Context, key string) (*types.CachedDetails, error) {
+	query := `UPDATE details_cache
+			  SET accessed_at = now()
+			  WHERE key = @key AND expires_at > now()
+			  RETURNING found, release_date, text, link, expires_at
+			 `
+
+	var (
+		cached  = &types.CachedDetails{Key: key}
+		details = new(types.Details)
+		found   bool
+	)
+
+	err := c.pool.QueryRow(ctx, query, pgx.NamedArgs{"key": key}).Scan(&found, &details.ReleaseDate, &details.Text, &details.Link, &cached.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if found {
+		cached.Details = details
+	}
+
+	return cached, nil
+}
+
+func (c *PostgresDetailsCache) Set(ctx context.Context, cached *types.CachedDetails) error {
+	if c.size <= 0 {
+		return nil
+	}
+
+	upsert := `INSERT INTO details_cache(key, found, release_date, text, link, expires_at)
+			   VALUES(@key, @found, @release_date, @text, @link, @expires_at)
+			   ON CONFLICT (key) DO UPDATE
+			   SET found = EXCLUDED.found, release_date = EXCLUDED.release_date, text = EXCLUDED.text,
+			       link = EXCLUDED.link, expires_at = EXCLUDED.expires_at, accessed_at = now()
+			   RETURNING xmax = 0
+			  `
+
+	// Only the entries beyond size are deleted, least recently used first.
+	evict := `DELETE FROM details_cache
+			  WHERE key IN (
+				  SELECT key FROM details_cache
+				  ORDER BY accessed_at
+				  LIMIT GREATEST((SELECT COUNT(*) FROM details_cache) - @size, 0)
+			  )
+			 `
+
+	args := pgx.NamedArgs{
+		"key":        cached.Key,
+		"found":      cached.Details != nil,
+		"expires_at": cached.ExpiresAt,
+	}
+
+	if d := cached.Details; d != nil {
+		args["release_date"] = d.ReleaseDate
+		args["text"] = d.Text
+		args["link"] = d.Link
+	} else {
+		args["release_date"] = ""
+		args["text"] = ""
+		args["link"] = ""
+	}
+
+	return pgx.BeginFunc(ctx, c.pool, func(tx pgx.Tx) error {
+		// xmax is 0 for an inserted row. Updating an entry doesn't grow the
+		// table, so there is nothing to evict then.
+		var inserted bool
+		if err := tx.QueryRow(ctx, upsert, args).Scan(&inserted); err != nil {
+			return err
+		}
+		if !inserted {
+			return nil
+		}
+		_, err := tx.Exec(ctx, evict, pgx.NamedArgs{"size": c.size})
+		return err
+	})
+}
+
+func (c *PostgresDetailsCache) Delete(ctx context.Context, key string) (bool, error) {
+	query := `DELETE FROM details_cache WHERE key = @key`
+
+	tag, err := c.pool.Exec(ctx, query, pgx.NamedArgs{"key": key})
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (c *PostgresDetailsCache) Clear(ctx context.Context) (int, error) {
+	tag, err := c.pool.Exec(ctx, `DELETE FROM details_cache`)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
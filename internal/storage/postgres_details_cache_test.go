@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/erknas/song-library/internal/types"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// newTestDetailsCache returns a cache of size over a temporary details_cache
+// table in the database at TEST_POSTGRES_DSN. The pool holds one connection,
+// so every statement sees the temporary table rather than a real one.
+func newTestDetailsCache(t *testing.T, size int) *PostgresDetailsCache {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+	cfg.MaxConns = 1
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	table := `CREATE TEMP TABLE details_cache (
+				key TEXT PRIMARY KEY,
+				found BOOLEAN NOT NULL,
+				release_date VARCHAR(10) NOT NULL DEFAULT '',
+				text TEXT NOT NULL DEFAULT '',
+				link TEXT NOT NULL DEFAULT '',
+				expires_at TIMESTAMPTZ NOT NULL,
+				accessed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			  )
+			 `
+
+	if _, err := pool.Exec(context.Background(), table); err != nil {
+		t.Fatalf("create details_cache: %v", err)
+	}
+
+	return (&PostgresPool{pool: pool}).DetailsCache(size)
+}
+
+func TestPostgresDetailsCacheEviction(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		// sets are cached in order; keys starting with "expired" have
+		// already expired.
+		sets []string
+		want []string
+	}{
+		{"within size", 3, []string{"a", "b", "c"}, []string{"a", "b", "c"}},
+		{"oldest evicted", 2, []string{"a", "b", "c"}, []string{"b", "c"}},
+		{"overwrite keeps an entry", 2, []string{"a", "b", "a", "c"}, []string{"a", "c"}},
+		{"overwrite doesn't evict", 2, []string{"a", "b", "b"}, []string{"a", "b"}},
+		// Expired entries used to be deleted by every Set, however much
+		// room was left.
+		{"expired kept within size", 3, []string{"expired", "a", "b"}, []string{"a", "b", "expired"}},
+		{"expired evicted like the others", 2, []string{"expired", "a", "b"}, []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := newTestDetailsCache(t, tt.size)
+
+			for _, key := range tt.sets {
+				expiresAt := time.Now().Add(time.Hour)
+				if key == "expired" {
+					expiresAt = time.Now().Add(-time.Hour)
+				}
+
+				if err := c.Set(ctx, &types.CachedDetails{Key: key, ExpiresAt: expiresAt}); err != nil {
+					t.Fatalf("Set(%q) error = %v", key, err)
+				}
+			}
+
+			rows, err := c.pool.Query(ctx, `SELECT key FROM details_cache ORDER BY key`)
+			if err != nil {
+				t.Fatalf("select keys: %v", err)
+			}
+
+			got, err := pgx.CollectRows(rows, pgx.RowTo[string])
+			if err != nil {
+				t.Fatalf("collect keys: %v", err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("cached keys = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/erknas/song-library/internal/types"
+	"github.com/jackc/pgx/v5"
+)
+
+const enrichmentColumns = `s.id, s.song, g.name, s.enrichment_status, s.enrichment_attempts, COALESCE(s.enrichment_error, ''),
+	s.enrichment_next_at, s.enrichment_updated_at`
+
+func (p *PostgresPool) SongEnrichment(ctx context.Context, id int) (*types.Enrichment, error) {
+	query := `SELECT ` + enrichmentColumns + `
+			  FROM songs s
+			  JOIN groups g ON g.id = s.group_id
+			  WHERE s.id=@id AND s.deleted_at IS NULL
+			 `
+
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	job, err := scanEnrichment(p.pool.QueryRow(ctx, query, args))
+	if err != nil {
+		return nil, rowError(err, songResource, id)
+	}
+
+	return job, nil
+}
+
+// EnrichmentJobs lists the songs whose enrichment has the given status, or
+// that aren't enriched yet when status is empty.
+func (p *PostgresPool) EnrichmentJobs(ctx context.Context, status string, pag types.Pagination) ([]*types.Enrichment, error) {
+	query := `SELECT ` + enrichmentColumns + `
+			  FROM songs s
+			  JOIN groups g ON g.id = s.group_id
+			  WHERE s.deleted_at IS NULL AND s.enrichment_status <> 'done'
+			  AND (@status = '' OR s.enrichment_status = @status)
+			  ORDER BY s.id
+			  LIMIT @size OFFSET @offset
+			 `
+
+	args := pgx.NamedArgs{
+		"status": status,
+		"size":   pag.Size,
+		"offset": pag.Offset(),
+	}
+
+	return p.queryEnrichments(ctx, query, args)
+}
+
+// ClaimEnrichmentJobs picks up to limit pending jobs that are due and
+// postpones them by lease, so other workers skip them while they run. A job
+// whose worker dies becomes due again once the lease runs out.
+func (p *PostgresPool) ClaimEnrichmentJobs(ctx context.Context, limit int, lease time.Duration) ([]*types.Enrichment, error) {
+	query := `UPDATE songs s
+			  SET enrichment_next_at = now() + make_interval(secs => @lease)
+			  FROM groups g
+			  WHERE g.id = s.group_id AND s.id IN (
+				  SELECT id FROM songs
+				  WHERE enrichment_status = 'pending' AND deleted_at IS NULL AND enrichment_next_at <= now()
+				  ORDER BY enrichment_next_at
+				  LIMIT @limit
+				  FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING ` + enrichmentColumns
+
+	args := pgx.NamedArgs{
+		"lease": lease.Seconds(),
+		"limit": limit,
+	}
+
+	return p.queryEnrichments(ctx, query, args)
+}
+
+// CompleteEnrichment fills in the details of song id. Fields the song got
+// in the meantime are kept.
+func (p *PostgresPool) CompleteEnrichment(ctx context.Context, id int, details *types.Song, opts types.WriteOptions) error {
+	query := `UPDATE songs
+			  SET release_date = COALESCE(release_date, @release_date),
+			  text = COALESCE(NULLIF(text, ''), @text),
+			  link = COALESCE(NULLIF(link, ''), @link),
+			  version = version + 1,
+			  enrichment_status = 'done',
+			  enrichment_attempts = enrichment_attempts + 1,
+			  enrichment_error = NULL,
+			  enrichment_next_at = NULL,
+			  enrichment_updated_at = now()
+			  WHERE id=@id AND deleted_at IS NULL AND enrichment_status = 'pending'
+			 `
+
+	args := pgx.NamedArgs{
+		"id":           id,
+		"release_date": details.ReleaseDate,
+		"text":         details.Text,
+		"link":         details.Link,
+	}
+
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, args)
+		if err != nil {
+			return pgError(err)
+		}
+
+		if tag.RowsAffected() == 0 {
+			return NotFound(songResource, id)
+		}
+
+		return recordRevision(ctx, tx, id, types.RevisionUpdate, opts.Author)
+	})
+}
+
+// FailEnrichment records a failed attempt at enriching song id. The job is
+// retried at retryAt, or given up on when retryAt is nil.
+func (p *PostgresPool) FailEnrichment(ctx context.Context, id int, msg string, retryAt *time.Time) error {
+	query := `UPDATE songs
+			  SET enrichment_status = CASE WHEN @retry_at::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+			  enrichment_attempts = enrichment_attempts + 1,
+			  enrichment_error = @error,
+			  enrichment_next_at = @retry_at,
+			  enrichment_updated_at = now()
+			  WHERE id=@id AND deleted_at IS NULL AND enrichment_status = 'pending'
+			 `
+
+	args := pgx.NamedArgs{
+		"id":       id,
+		"error":    msg,
+		"retry_at": retryAt,
+	}
+
+	tag, err := p.pool.Exec(ctx, query, args)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return NotFound(songResource, id)
+	}
+
+	return nil
+}
+
+// RetryEnrichment queues song id for enrichment again with a fresh set of
+// attempts. Songs that are already enriched aren't queued.
+func (p *PostgresPool) RetryEnrichment(ctx context.Context, id int) error {
+	query := `UPDATE songs
+			  SET enrichment_status = 'pending',
+			  enrichment_attempts = 0,
+			  enrichment_error = NULL,
+			  enrichment_next_at = now(),
+			  enrichment_updated_at = now()
+			  WHERE id=@id AND deleted_at IS NULL AND enrichment_status <> 'done'
+			 `
+
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, args)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return enrichmentNotRetried(ctx, tx, id)
+		}
+
+		return nil
+	})
+}
+
+// enrichmentNotRetried explains why retrying the enrichment of song id
+// affected no rows: either the song doesn't exist or it is enriched already.
+func enrichmentNotRetried(ctx context.Context, tx pgx.Tx, id int) error {
+	query := `SELECT 1
+			  FROM songs
+			  WHERE id=@id AND deleted_at IS NULL
+			 `
+
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	var one int
+
+	if err := tx.QueryRow(ctx, query, args).Scan(&one); err != nil {
+		return rowError(err, songResource, id)
+	}
+
+	return ErrEnriched
+}
+
+func (p *PostgresPool) queryEnrichments(ctx context.Context, query string, args pgx.NamedArgs) ([]*types.Enrichment, error) {
+	rows, err := p.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*types.Enrichment
+
+	for rows.Next() {
+		job, err := scanEnrichment(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func scanEnrichment(row pgx.Row) (*types.Enrichment, error) {
+	job := new(types.Enrichment)
+
+	err := row.Scan(&job.SongID, &job.Song, &job.Group, &job.Status, &job.Attempts, &job.LastError,
+		&job.NextAttemptAt, &job.UpdatedAt)
+
+	return job, err
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/erknas/song-library/internal/types"
+	"github.com/jackc/pgx/v5"
+)
+
+// exportBatch is the number of songs fetched from the export cursor at a
+// time.
+const exportBatch = 500
+
+// ExportSongs calls fn for every song matching f in ID order. The songs are
+// read through a cursor in a read-only snapshot, so memory use doesn't grow
+// with the library and the export is consistent even while songs change.
+func (p *PostgresPool) ExportSongs(ctx context.Context, f types.Filter, fn func(*types.Song) error) error {
+	query, args := exportQuery(f)
+
+	txOpts := pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	}
+
+	return pgx.BeginTxFunc(ctx, p.pool, txOpts, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "DECLARE export_songs NO SCROLL CURSOR FOR "+query, args); err != nil {
+			return err
+		}
+
+		fetch := fmt.Sprintf("FETCH FORWARD %d FROM export_songs", exportBatch)
+
+		for {
+			n, err := fetchSongs(ctx, tx, fetch, fn)
+			if err != nil {
+				return err
+			}
+
+			if n < exportBatch {
+				return nil
+			}
+		}
+	})
+}
+
+// fetchSongs runs one FETCH of a song cursor and returns the number of
+// songs passed to fn.
+func fetchSongs(ctx context.Context, tx pgx.Tx, fetch string, fn func(*types.Song) error) (int, error) {
+	rows, err := tx.Query(ctx, fetch)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+
+	for rows.Next() {
+		song := new(types.Song)
+		if err := rows.Scan(&song.ID, &song.Song, &song.GroupID, &song.Group, &song.ReleaseDate, &song.Text, &song.Link, &song.AlbumID, &song.TrackNumber, &song.Version, &song.EnrichmentStatus); err != nil {
+			return n, err
+		}
+
+		if err := fn(song); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, rows.Err()
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/erknas/song-library/internal/types"
+	"github.com/jackc/pgx/v5"
+)
+
+func (p *PostgresPool) Groups(ctx context.Context, pag types.Pagination) ([]*types.Group, error) {
+	query := `SELECT id, name
+			  FROM groups
+			  ORDER BY LOWER(name), id
+			  LIMIT @size
+			  OFFSET @offset
+			 `
+
+	args := pgx.NamedArgs{
+		"size":   pag.Size,
+		"offset": pag.Offset(),
+	}
+
+	rows, err := p.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []*types.Group
+
+	for rows.Next() {
+		group := new(types.Group)
+		if err := rows.Scan(&group.ID, &group.Name); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func (p *PostgresPool) Group(ctx context.Context, id int) (*types.Group, error) {
+	query := `SELECT id, name
+			  FROM groups
+			  WHERE id=@id
+			 `
+
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	group := new(types.Group)
+
+	if err := p.pool.QueryRow(ctx, query, args).Scan(&group.ID, &group.Name); err != nil {
+		return nil, rowError(err, groupResource, id)
+	}
+
+	return group, nil
+}
+
+func (p *PostgresPool) AddGroup(ctx context.Context, name string) (*types.Group, error) {
+	query := `INSERT INTO groups(name)
+			  VALUES(@name)
+			  RETURNING id, name
+			 `
+
+	args := pgx.NamedArgs{
+		"name": name,
+	}
+
+	group := new(types.Group)
+
+	if err := p.pool.QueryRow(ctx, query, args).Scan(&group.ID, &group.Name); err != nil {
+		return nil, pgError(err)
+	}
+
+	return group, nil
+}
+
+func (p *PostgresPool) UpdateGroup(ctx context.Context, id int, name string) error {
+	query := `UPDATE groups
+			  SET name=@name
+			  WHERE id=@id
+			 `
+
+	args := pgx.NamedArgs{
+		"name": name,
+		"id":   id,
+	}
+
+	tag, err := p.pool.Exec(ctx, query, args)
+	if err != nil {
+		return pgError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return NotFound(groupResource, id)
+	}
+
+	return nil
+}
+
+func (p *PostgresPool) DeleteGroup(ctx context.Context, id int) error {
+	query := `DELETE FROM groups
+			  WHERE id=@id
+			 `
+
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	tag, err := p.pool.Exec(ctx, query, args)
+	if err != nil {
+		return pgError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return NotFound(groupResource, id)
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"slices"
+
+	"github.com/erknas/song-library/internal/types"
+	"github.com/jackc/pgx/v5"
+)
+
+// ImportSongs adds songs in one transaction and reports what was done with
+// each of them, in the same order. A song that already exists is updated if
+// onConflict is types.OnConflictUpdate and skipped otherwise; its album and
+// track number are only replaced by a song naming an album. The songs must
+// not repeat each other or take the same track. The rows are streamed into a temporary table with
+// COPY, so a large import costs a handful of statements instead of one per
+// song.
+func (p *PostgresPool) ImportSongs(ctx context.Context, songs []*types.Song, onConflict string, opts types.WriteOptions) ([]types.ImportedSong, error) {
+	imported := make([]types.ImportedSong, len(songs))
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		// The IDs are taken from the sequence up front and handed out in the
+		// order of the songs. Every row keeps the position of its song in idx,
+		// which the report is read back by.
+		rows, err := tx.Query(ctx, `SELECT nextval(pg_get_serial_sequence('songs', 'id')) FROM generate_series(1, @n)`,
+			pgx.NamedArgs{"n": len(songs)})
+		if err != nil {
+			return err
+		}
+
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			return err
+		}
+
+		slices.Sort(ids)
+
+		create := `CREATE TEMP TABLE import_songs (
+					   idx INT PRIMARY KEY,
+					   id INT NOT NULL,
+					   song VARCHAR(255) NOT NULL,
+					   group_name VARCHAR(255) NOT NULL,
+					   release_date DATE NOT NULL,
+					   text TEXT,
+					   link VARCHAR(255),
+					   album_id INT,
+					   track_number INT,
+					   group_id INT,
+					   existing_id INT
+				   ) ON COMMIT DROP
+				  `
+
+		if _, err := tx.Exec(ctx, create); err != nil {
+			return err
+		}
+
+		columns := []string{"idx", "id", "song", "group_name", "release_date", "text", "link", "album_id", "track_number"}
+
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{"import_songs"}, columns,
+			pgx.CopyFromSlice(len(songs), func(i int) ([]any, error) {
+				song := songs[i]
+				return []any{i, ids[i], song.Song, song.Group, song.ReleaseDate, song.Text, song.Link, song.AlbumID, song.TrackNumber}, nil
+			}))
+		if err != nil {
+			return pgError(err)
+		}
+
+		// A new group is named after its first song in the file.
+		groups := `INSERT INTO groups(name)
+				   SELECT DISTINCT ON (LOWER(group_name)) group_name
+				   FROM import_songs
+				   ORDER BY LOWER(group_name), idx
+				   ON CONFLICT ((LOWER(name))) DO NOTHING
+				  `
+
+		if _, err := tx.Exec(ctx, groups); err != nil {
+			return pgError(err)
+		}
+
+		resolve := `UPDATE import_songs i
+					SET group_id = g.id
+					FROM groups g
+					WHERE LOWER(g.name) = LOWER(i.group_name)
+				   `
+
+		if _, err := tx.Exec(ctx, resolve); err != nil {
+			return err
+		}
+
+		existing := `UPDATE import_songs i
+					 SET existing_id = s.id
+					 FROM songs s
+					 WHERE s.group_id = i.group_id AND s.deleted_at IS NULL
+					 AND ` + songNameKey("s.song") + ` = ` + songNameKey("i.song") + `
+					`
+
+		if _, err := tx.Exec(ctx, existing); err != nil {
+			return err
+		}
+
+		if onConflict == types.OnConflictUpdate {
+			update := `UPDATE songs s
+					   SET song = i.song, release_date = i.release_date, text = i.text, link = i.link,
+					       album_id = COALESCE(i.album_id, s.album_id),
+					       track_number = CASE WHEN i.album_id IS NULL THEN s.track_number ELSE i.track_number END,
+					       version = s.version + 1, enrichment_status = 'done', enrichment_error = NULL,
+					       enrichment_next_at = NULL, enrichment_updated_at = now()
+					   FROM import_songs i
+					   WHERE s.id = i.existing_id
+					  `
+
+			if _, err := tx.Exec(ctx, update); err != nil {
+				return pgError(err)
+			}
+		}
+
+		insert := `INSERT INTO songs(id, song, group_id, release_date, text, link, album_id, track_number)
+				   SELECT id, song, group_id, release_date, text, link, album_id, track_number
+				   FROM import_songs
+				   WHERE existing_id IS NULL
+				   ORDER BY idx
+				  `
+
+		if _, err := tx.Exec(ctx, insert); err != nil {
+			return pgError(err)
+		}
+
+		rows, err = tx.Query(ctx, `SELECT idx, id, COALESCE(existing_id, 0) FROM import_songs ORDER BY idx`)
+		if err != nil {
+			return err
+		}
+
+		outcomes, err := pgx.CollectRows(rows, pgx.RowToStructByPos[struct{ Idx, ID, ExistingID int }])
+		if err != nil {
+			return err
+		}
+
+		var added, updated []int
+
+		for _, o := range outcomes {
+			switch {
+			case o.ExistingID == 0:
+				imported[o.Idx] = types.ImportedSong{ID: o.ID, Action: types.ImportAdded}
+				added = append(added, o.ID)
+			case onConflict == types.OnConflictUpdate:
+				imported[o.Idx] = types.ImportedSong{ID: o.ExistingID, Action: types.ImportUpdated}
+				updated = append(updated, o.ExistingID)
+			default:
+				imported[o.Idx] = types.ImportedSong{ID: o.ExistingID, Action: types.ImportSkipped}
+			}
+		}
+
+		if err := recordRevisions(ctx, tx, added, types.RevisionAdd, opts.Author); err != nil {
+			return err
+		}
+
+		return recordRevisions(ctx, tx, updated, types.RevisionUpdate, opts.Author)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return imported, nil
+}
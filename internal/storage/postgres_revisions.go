@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/erknas/song-library/internal/types"
+	"github.com/jackc/pgx/v5"
+)
+
+const revisionColumns = `id, song_id, version, action, author, created_at, song, group_name, release_date, text, link, album_id, track_number`
+
+func (p *PostgresPool) SongRevisions(ctx context.Context, id int, pag types.Pagination) ([]*types.Revision, error) {
+	query := `SELECT ` + revisionColumns + `
+			  FROM song_revisions
+			  WHERE song_id=@id
+			  ORDER BY id DESC
+			  LIMIT @size
+			  OFFSET @offset
+			 `
+
+	args := pgx.NamedArgs{
+		"id":     id,
+		"size":   pag.Size,
+		"offset": pag.Offset(),
+	}
+
+	rows, err := p.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*types.Revision
+
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (p *PostgresPool) SongRevision(ctx context.Context, id, revID int) (*types.Revision, error) {
+	query := `SELECT ` + revisionColumns + `
+			  FROM song_revisions
+			  WHERE song_id=@id AND id=@rev_id
+			 `
+
+	args := pgx.NamedArgs{
+		"id":     id,
+		"rev_id": revID,
+	}
+
+	rev, err := scanRevision(p.pool.QueryRow(ctx, query, args))
+	if err != nil {
+		return nil, rowError(err, revisionResource, revID)
+	}
+
+	return rev, nil
+}
+
+// RestoreSong puts song id back to the state in song. A deleted song is
+// added again under its old ID, at the version after its last revision.
+func (p *PostgresPool) RestoreSong(ctx context.Context, id int, song *types.Song, opts types.WriteOptions) error {
+	lock := `SELECT 1
+			 FROM songs
+			 WHERE id=@id
+			 FOR UPDATE
+			`
+
+	insert := upsertGroup + `
+			  INSERT INTO songs(id, song, group_id, release_date, text, link, album_id, track_number, version)
+			  SELECT @id, @song, grp.id, @release_date, @text, @link, @album_id, @track_number,
+			  (SELECT COALESCE(MAX(version), 0) + 1 FROM song_revisions WHERE song_id=@id)
+			  FROM grp
+			 `
+
+	query, args := updateSongQuery(id, song, opts)
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		var one int
+
+		err := tx.QueryRow(ctx, lock, args).Scan(&one)
+
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			if opts.IfVersion != 0 {
+				return NotFound(songResource, id)
+			}
+			if _, err := tx.Exec(ctx, insert, args); err != nil {
+				return pgError(err)
+			}
+		case err != nil:
+			return err
+		default:
+			// Restoring a song from the trash takes it out of there.
+			if _, err := tx.Exec(ctx, `UPDATE songs SET deleted_at=NULL WHERE id=@id`, args); err != nil {
+				return pgError(err)
+			}
+			tag, err := tx.Exec(ctx, query, args)
+			if err != nil {
+				return pgError(err)
+			}
+			if tag.RowsAffected() == 0 {
+				return songUnchanged(ctx, tx, id)
+			}
+		}
+
+		return recordRevision(ctx, tx, id, types.RevisionRestore, opts.Author)
+	})
+
+	return p.duplicateSong(ctx, err, id, song.Song, song.Group)
+}
+
+// recordRevision snapshots the current state of song id. It runs in the
+// transaction of the change, so a failed change leaves no revision behind.
+func recordRevision(ctx context.Context, tx pgx.Tx, id int, action, author string) error {
+	return recordRevisions(ctx, tx, []int{id}, action, author)
+}
+
+// recordRevisions is recordRevision for several songs changed at once.
+func recordRevisions(ctx context.Context, tx pgx.Tx, ids []int, action, author string) error {
+	query := `INSERT INTO song_revisions(song_id, version, action, song, group_name, release_date, text, link, album_id, track_number, author)
+			  SELECT s.id, s.version, @action, s.song, g.name, s.release_date, s.text, s.link, s.album_id, s.track_number, @author
+			  FROM songs s
+			  JOIN groups g ON g.id = s.group_id
+			  WHERE s.id = ANY(@ids)
+			  ORDER BY s.id
+			 `
+
+	args := pgx.NamedArgs{
+		"ids":    ids,
+		"action": action,
+		"author": author,
+	}
+
+	_, err := tx.Exec(ctx, query, args)
+
+	return err
+}
+
+func scanRevision(row pgx.Row) (*types.Revision, error) {
+	rev := new(types.Revision)
+
+	err := row.Scan(&rev.ID, &rev.SongID, &rev.Version, &rev.Action, &rev.Author, &rev.CreatedAt,
+		&rev.Song, &rev.Group, &rev.ReleaseDate, &rev.Text, &rev.Link, &rev.AlbumID, &rev.TrackNumber)
+
+	return rev, err
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/erknas/song-library/internal/types"
+	"github.com/jackc/pgx/v5"
+)
+
+func (p *PostgresPool) TrashedSongs(ctx context.Context, pag types.Pagination) ([]*types.Song, error) {
+	query := `SELECT s.id, s.song, s.group_id, g.name, s.release_date, s.text, s.link, s.album_id, s.track_number, s.version, s.enrichment_status, s.deleted_at
+			  FROM songs s
+			  JOIN groups g ON g.id = s.group_id
+			  WHERE s.deleted_at IS NOT NULL
+			  ORDER BY s.deleted_at DESC, s.id
+			  LIMIT @size
+			  OFFSET @offset
+			 `
+
+	args := pgx.NamedArgs{
+		"size":   pag.Size,
+		"offset": pag.Offset(),
+	}
+
+	rows, err := p.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var songs []*types.Song
+
+	for rows.Next() {
+		song := new(types.Song)
+		if err := rows.Scan(&song.ID, &song.Song, &song.GroupID, &song.Group, &song.ReleaseDate, &song.Text, &song.Link, &song.AlbumID, &song.TrackNumber, &song.Version, &song.EnrichmentStatus, &song.DeletedAt); err != nil {
+			return nil, err
+		}
+		songs = append(songs, song)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return songs, nil
+}
+
+// UndeleteSong takes song id out of the trash.
+func (p *PostgresPool) UndeleteSong(ctx context.Context, id int, opts types.WriteOptions) error {
+	query := `UPDATE songs
+			  SET deleted_at=NULL, version=version+1
+			  WHERE id=@id AND deleted_at IS NOT NULL
+			 `
+
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, args)
+		if err != nil {
+			return pgError(err)
+		}
+
+		if tag.RowsAffected() == 0 {
+			return NotFound(songResource, id)
+		}
+
+		return recordRevision(ctx, tx, id, types.RevisionRestore, opts.Author)
+	})
+	if !errors.Is(err, ErrDuplicateSong) {
+		return err
+	}
+
+	var song, group string
+
+	name := `SELECT s.song, g.name
+			 FROM songs s
+			 JOIN groups g ON g.id = s.group_id
+			 WHERE s.id=@id
+			`
+
+	if lookupErr := p.pool.QueryRow(ctx, name, args).Scan(&song, &group); lookupErr != nil {
+		return err
+	}
+
+	return p.duplicateSong(ctx, err, id, song, group)
+}
+
+// PurgeSongs hard-deletes the songs moved to the trash before before and
+// returns how many there were. Their revisions are kept.
+func (p *PostgresPool) PurgeSongs(ctx context.Context, before time.Time) (int, error) {
+	query := `DELETE FROM songs
+			  WHERE deleted_at < @before
+			 `
+
+	args := pgx.NamedArgs{
+		"before": before,
+	}
+
+	tag, err := p.pool.Exec(ctx, query, args)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,336 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/erknas/song-library/internal/types"
+	"github.com/jackc/pgx/v5"
+)
+
+// sortColumns maps each sort field to the SQL expression songs are ordered
+// by and to the same expression applied to the cursor argument. The
+// expressions refer to the columns of the filtered CTE built by songsQuery.
+// Songs without a release date yet sort after all others, in descending
+// order as well thanks to descSortColumns.
+var sortColumns = map[string]sortExpr{
+	types.SortByRank:        {"rank", "@cursor_rank"},
+	types.SortByID:          {"id", "@cursor_id"},
+	types.SortBySong:        {"LOWER(song)", "LOWER(@cursor_song)"},
+	types.SortByGroup:       {"LOWER(group_name)", "LOWER(@cursor_group)"},
+	types.SortByReleaseDate: {"COALESCE(release_date, 'infinity')", "COALESCE(@cursor_date::date, 'infinity')"},
+}
+
+// descSortColumns overrides sortColumns for descending keys.
+var descSortColumns = map[string]sortExpr{
+	types.SortByReleaseDate: {"COALESCE(release_date, '-infinity')", "COALESCE(@cursor_date::date, '-infinity')"},
+}
+
+type sortExpr struct {
+	expr, cursor string
+}
+
+func songsQuery(q types.SongsQuery) (string, pgx.NamedArgs, error) {
+	where, rank, args := songsFilter(q.Filter)
+
+	keys := q.SortKeys()
+
+	orderBy, err := orderBy(keys)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The total is counted over the filtered songs before the cursor is
+	// applied, so it stays the same on every page.
+	query := `WITH filtered AS (
+				SELECT s.id, s.song, s.group_id, g.name AS group_name, s.release_date, s.text, s.link,
+				s.album_id, s.track_number, s.version, s.enrichment_status,
+				` + rank + ` AS rank,
+				COUNT(*) OVER() AS total
+				FROM songs s
+				JOIN groups g ON g.id = s.group_id` + where + `
+			  )
+			  SELECT id, song, group_id, group_name, release_date, text, link, album_id, track_number, version, enrichment_status, rank, total
+			  FROM filtered`
+
+	if c := q.Pagination.Cursor; c != nil {
+		after, err := afterCursor(keys)
+		if err != nil {
+			return "", nil, err
+		}
+
+		query += " WHERE " + after
+		args["cursor_id"] = c.ID
+		args["cursor_song"] = c.Song
+		args["cursor_group"] = c.Group
+		args["cursor_date"] = c.ReleaseDate
+		args["cursor_rank"] = c.Rank
+	}
+
+	query += " ORDER BY " + orderBy + " LIMIT @size OFFSET @offset"
+
+	// One extra row tells whether there is a next page.
+	args["size"] = q.Pagination.Size + 1
+	args["offset"] = q.Pagination.Offset()
+
+	return query, args, nil
+}
+
+// songsFilter builds the WHERE clause selecting the live songs matching f
+// and the fuzzy match rank. It refers to songs as s and groups as g.
+func songsFilter(f types.Filter) (string, string, pgx.NamedArgs) {
+	var (
+		conds = []string{"s.deleted_at IS NULL"}
+		ranks []string
+		args  = pgx.NamedArgs{}
+	)
+
+	textConds := []struct {
+		col, arg, value string
+	}{
+		{"s.song", "song", f.Song},
+		{"g.name", "group_name", f.Group},
+	}
+
+	for _, c := range textConds {
+		if c.value == "" {
+			continue
+		}
+
+		switch f.Match {
+		case types.MatchPrefix:
+			conds = append(conds, fmt.Sprintf("%s ILIKE @%s", c.col, c.arg))
+			args[c.arg] = escapeLike(c.value) + "%"
+		case types.MatchFuzzy:
+			conds = append(conds, fmt.Sprintf("(%s ILIKE @%s_like OR @%s <%% %s)", c.col, c.arg, c.arg, c.col))
+			ranks = append(ranks, fmt.Sprintf("word_similarity(@%s, %s)", c.arg, c.col))
+			args[c.arg] = c.value
+			args[c.arg+"_like"] = "%" + escapeLike(c.value) + "%"
+		default:
+			conds = append(conds, fmt.Sprintf("LOWER(%s)=LOWER(@%s)", c.col, c.arg))
+			args[c.arg] = c.value
+		}
+	}
+
+	if f.GroupID != 0 {
+		conds = append(conds, "s.group_id=@group_id")
+		args["group_id"] = f.GroupID
+	}
+
+	if f.Date != nil {
+		conds = append(conds, "s.release_date=@release_date")
+		args["release_date"] = *f.Date
+	}
+
+	if f.DateFrom != nil {
+		conds = append(conds, "s.release_date>=@date_from")
+		args["date_from"] = *f.DateFrom
+	}
+
+	if f.DateTo != nil {
+		conds = append(conds, "s.release_date<=@date_to")
+		args["date_to"] = *f.DateTo
+	}
+
+	rank := "0::real"
+	if len(ranks) > 0 {
+		rank = "(" + strings.Join(ranks, " + ") + ")"
+	}
+
+	return " WHERE " + strings.Join(conds, " AND "), rank, args
+}
+
+// exportQuery selects every song matching f in ID order.
+func exportQuery(f types.Filter) (string, pgx.NamedArgs) {
+	where, _, args := songsFilter(f)
+
+	query := `SELECT s.id, s.song, s.group_id, g.name, s.release_date, s.text, s.link, s.album_id, s.track_number, s.version, s.enrichment_status
+			  FROM songs s
+			  JOIN groups g ON g.id = s.group_id` + where + `
+			  ORDER BY s.id`
+
+	return query, args
+}
+
+func sortColumn(k types.SortField) (string, string, error) {
+	if col, ok := descSortColumns[k.Field]; ok && k.Desc {
+		return col.expr, col.cursor, nil
+	}
+
+	col, ok := sortColumns[k.Field]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported sort field %q", k.Field)
+	}
+
+	return col.expr, col.cursor, nil
+}
+
+func orderBy(keys []types.SortField) (string, error) {
+	terms := make([]string, 0, len(keys))
+
+	for _, k := range keys {
+		expr, _, err := sortColumn(k)
+		if err != nil {
+			return "", err
+		}
+
+		dir := "ASC"
+		if k.Desc {
+			dir = "DESC"
+		}
+
+		terms = append(terms, expr+" "+dir)
+	}
+
+	return strings.Join(terms, ", "), nil
+}
+
+// afterCursor builds the keyset predicate selecting rows that come after
+// the cursor in the given ordering:
+//
+//	k1 > c1 OR (k1 = c1 AND k2 > c2) OR ...
+//
+// with < instead of > for descending keys.
+func afterCursor(keys []types.SortField) (string, error) {
+	var (
+		terms []string
+		eqs   []string
+	)
+
+	for _, k := range keys {
+		expr, cursor, err := sortColumn(k)
+		if err != nil {
+			return "", err
+		}
+
+		op := ">"
+		if k.Desc {
+			op = "<"
+		}
+
+		term := append(append([]string{}, eqs...), fmt.Sprintf("%s %s %s", expr, op, cursor))
+		terms = append(terms, "("+strings.Join(term, " AND ")+")")
+		eqs = append(eqs, fmt.Sprintf("%s = %s", expr, cursor))
+	}
+
+	return "(" + strings.Join(terms, " OR ") + ")", nil
+}
+
+// newSongsPage trims the extra row fetched to detect a next page and, when
+// there is one, returns a cursor pointing at the last song of this page.
+func newSongsPage(q types.SongsQuery, songs []*types.Song, ranks []float64, total int) *types.SongsPage {
+	page := &types.SongsPage{
+		Songs: songs,
+		Total: total,
+	}
+
+	if len(songs) <= q.Pagination.Size {
+		return page
+	}
+
+	page.Songs = songs[:q.Pagination.Size]
+	last := page.Songs[len(page.Songs)-1]
+
+	page.Next = &types.Cursor{
+		Sort:        q.SortSignature(),
+		ID:          last.ID,
+		Song:        last.Song,
+		Group:       last.Group,
+		ReleaseDate: last.ReleaseDate,
+		Rank:        ranks[len(page.Songs)-1],
+	}
+
+	return page
+}
+
+// updateSongQuery builds an UPDATE of every column of song id.
+func updateSongQuery(id int, song *types.Song, opts types.WriteOptions) (string, pgx.NamedArgs) {
+	query := upsertGroup + `
+			  UPDATE songs 
+			  SET
+			  song=@song,
+			  group_id=(SELECT id FROM grp), 
+			  release_date=@release_date, 
+			  text=@text, 
+			  link=@link,
+			  album_id=@album_id,
+			  track_number=@track_number,
+			  version=version+1
+			  WHERE id=@id AND deleted_at IS NULL` + ifVersion + `
+			 `
+
+	args := pgx.NamedArgs{
+		"song":         song.Song,
+		"group_name":   song.Group,
+		"release_date": song.ReleaseDate,
+		"text":         song.Text,
+		"link":         song.Link,
+		"album_id":     song.AlbumID,
+		"track_number": song.TrackNumber,
+		"id":           id,
+		"if_version":   opts.IfVersion,
+	}
+
+	return query, args
+}
+
+// patchSongQuery builds an UPDATE of song id setting only the columns
+// changed by p. The group is resolved through upsertGroup when it changes.
+func patchSongQuery(id int, p *types.SongPatch, opts types.WriteOptions) (string, pgx.NamedArgs) {
+	var (
+		sets = []string{"version=version+1"}
+		args = pgx.NamedArgs{
+			"id":         id,
+			"if_version": opts.IfVersion,
+		}
+	)
+
+	set := func(column string, value any) {
+		sets = append(sets, fmt.Sprintf("%s=@%s", column, column))
+		args[column] = value
+	}
+
+	if p.Song != nil {
+		set("song", *p.Song)
+	}
+	if p.ReleaseDate != nil {
+		set("release_date", *p.ReleaseDate)
+	}
+	if p.Text != nil {
+		set("text", *p.Text)
+	}
+	if p.Link != nil {
+		set("link", *p.Link)
+	}
+	if p.AlbumID.Set {
+		set("album_id", p.AlbumID.Value)
+	}
+	if p.TrackNumber.Set {
+		set("track_number", p.TrackNumber.Value)
+	}
+
+	query := ""
+	if p.Group != nil {
+		query = upsertGroup
+		sets = append(sets, "group_id=(SELECT id FROM grp)")
+		args["group_name"] = *p.Group
+	}
+
+	query += `
+			  UPDATE songs
+			  SET ` + strings.Join(sets, ", ") + `
+			  WHERE id=@id AND deleted_at IS NULL` + ifVersion + `
+			 `
+
+	return query, args
+}
+
+// ifVersion limits a song change to the version in @if_version, unless
+// it is zero.
+const ifVersion = ` AND (@if_version::int = 0 OR version=@if_version)`
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
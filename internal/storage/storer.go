@@ -2,15 +2,44 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/erknas/song-library/internal/types"
 )
 
 type Storer interface {
-	Songs(context.Context, types.Pagination) ([]*types.Song, error)
-	SongsByFilters(context.Context, string, []any) ([]*types.Song, error)
+	Songs(context.Context, types.SongsQuery) (*types.SongsPage, error)
+	SongByID(context.Context, int) (*types.Song, error)
+	SearchSongs(context.Context, types.SearchQuery) ([]*types.SearchResult, error)
 	SongText(context.Context, int) (string, error)
-	DeleteSong(context.Context, int) error
-	UpdateSong(context.Context, int, *types.Song) error
-	AddSong(context.Context, *types.Song) error
+	DeleteSong(context.Context, int, types.WriteOptions) error
+	UpdateSong(context.Context, int, *types.Song, types.WriteOptions) error
+	PatchSong(context.Context, int, *types.SongPatch, types.WriteOptions) error
+	AddSong(context.Context, *types.Song, types.WriteOptions) (*types.Song, error)
+	ImportSongs(context.Context, []*types.Song, string, types.WriteOptions) ([]types.ImportedSong, error)
+	ExportSongs(context.Context, types.Filter, func(*types.Song) error) error
+	SongRevisions(context.Context, int, types.Pagination) ([]*types.Revision, error)
+	SongRevision(context.Context, int, int) (*types.Revision, error)
+	RestoreSong(context.Context, int, *types.Song, types.WriteOptions) error
+	TrashedSongs(context.Context, types.Pagination) ([]*types.Song, error)
+	UndeleteSong(context.Context, int, types.WriteOptions) error
+	PurgeSongs(context.Context, time.Time) (int, error)
+	SongEnrichment(context.Context, int) (*types.Enrichment, error)
+	EnrichmentJobs(context.Context, string, types.Pagination) ([]*types.Enrichment, error)
+	ClaimEnrichmentJobs(context.Context, int, time.Duration) ([]*types.Enrichment, error)
+	CompleteEnrichment(context.Context, int, *types.Song, types.WriteOptions) error
+	FailEnrichment(context.Context, int, string, *time.Time) error
+	RetryEnrichment(context.Context, int) error
+	Groups(context.Context, types.Pagination) ([]*types.Group, error)
+	Group(context.Context, int) (*types.Group, error)
+	AddGroup(context.Context, string) (*types.Group, error)
+	UpdateGroup(context.Context, int, string) error
+	DeleteGroup(context.Context, int) error
+	Albums(context.Context, types.Pagination) ([]*types.Album, error)
+	Album(context.Context, int) (*types.Album, error)
+	AddAlbum(context.Context, *types.Album) (*types.Album, error)
+	UpdateAlbum(context.Context, int, *types.Album) error
+	DeleteAlbum(context.Context, int) error
+	AlbumTracks(context.Context, int) ([]*types.Song, error)
+	Close()
 }
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"strings"
+	"unicode"
+)
+
+// wordSimilarityThreshold matches the pg_trgm default used by the <% operator.
+const wordSimilarityThreshold = 0.6
+
+// trigrams returns the trigram set of words the way pg_trgm builds it: each
+// word is padded with two leading spaces and one trailing space.
+func trigrams(words []string) map[string]struct{} {
+	set := make(map[string]struct{})
+
+	for _, w := range words {
+		r := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			set[string(r[i:i+3])] = struct{}{}
+		}
+	}
+
+	return set
+}
+
+func trgmWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	common := 0
+	for t := range a {
+		if _, ok := b[t]; ok {
+			common++
+		}
+	}
+
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// wordSimilarity approximates pg_trgm's word_similarity(needle, haystack):
+// the best similarity between the needle and any run of consecutive words
+// of the haystack.
+func wordSimilarity(needle, haystack string) float64 {
+	var (
+		n     = trigrams(trgmWords(needle))
+		words = trgmWords(haystack)
+		best  float64
+	)
+
+	for i := range words {
+		for j := i + 1; j <= len(words); j++ {
+			if sim := similarity(n, trigrams(words[i:j])); sim > best {
+				best = sim
+			}
+		}
+	}
+
+	return best
+}
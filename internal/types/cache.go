@@ -0,0 +1,15 @@
+package types
+
+import "time"
+
+// CachedDetails is a details lookup kept in the details cache under Key.
+// Details is nil if the provider had no details for the song.
+type CachedDetails struct {
+	Key       string
+	Details   *Details
+	ExpiresAt time.Time
+}
+
+type CacheInvalidation struct {
+	Invalidated int `json:"invalidated"`
+}
@@ -0,0 +1,27 @@
+package types
+
+import "time"
+
+// Enrichment statuses. A song is pending until its details have been
+// fetched, and failed once every attempt has.
+const (
+	EnrichmentPending = "pending"
+	EnrichmentDone    = "done"
+	EnrichmentFailed  = "failed"
+)
+
+// Enrichment is the background job fetching the details of a song.
+type Enrichment struct {
+	SongID        int        `json:"songId"`
+	Song          string     `json:"song"`
+	Group         string     `json:"group"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"lastError,omitempty"`
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	UpdatedAt     *time.Time `json:"updatedAt,omitempty"`
+}
+
+type Enrichments struct {
+	Jobs []*Enrichment `json:"jobs"`
+}
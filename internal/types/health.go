@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+// Health statuses.
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+// Health is degraded while any details provider is down. Songs are still
+// added then, and enriched once the provider is back.
+type Health struct {
+	Status    string           `json:"status"`
+	Providers []ProviderHealth `json:"providers"`
+}
+
+// ProviderHealth describes a details provider and, for one calling an
+// upstream, the state of its circuit breaker.
+type ProviderHealth struct {
+	Provider string     `json:"provider"`
+	Up       bool       `json:"up"`
+	Breaker  string     `json:"breaker,omitempty"`
+	Failures int        `json:"failures,omitempty"`
+	RetryAt  *time.Time `json:"retryAt,omitempty"`
+}
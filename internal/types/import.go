@@ -0,0 +1,56 @@
+package types
+
+// Import conflict policies: what happens to a row naming a song that
+// already exists.
+const (
+	OnConflictError  = "error"
+	OnConflictSkip   = "skip"
+	OnConflictUpdate = "update"
+)
+
+// Import actions, what was done with a row.
+const (
+	ImportAdded   = "added"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped"
+)
+
+// ImportRow is one song of a bulk import. Rows without a release date are
+// enriched through the details provider; text and link are optional.
+type ImportRow struct {
+	Line        int    `json:"-"`
+	Song        string `json:"song"`
+	Group       string `json:"group"`
+	ReleaseDate string `json:"releaseDate,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Link        string `json:"link,omitempty"`
+	AlbumID     *int   `json:"albumId,omitempty"`
+	TrackNumber *int   `json:"trackNumber,omitempty"`
+
+	// Err is set when the row itself could not be parsed.
+	Err error `json:"-"`
+}
+
+// ImportResult reports the outcome of one row of a bulk import by its line
+// in the uploaded file.
+type ImportResult struct {
+	Line   int    `json:"line"`
+	ID     int    `json:"id,omitempty"`
+	Action string `json:"action,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	Imported int            `json:"imported"`
+	Updated  int            `json:"updated"`
+	Skipped  int            `json:"skipped"`
+	Failed   int            `json:"failed"`
+	Results  []ImportResult `json:"results"`
+}
+
+// ImportedSong is what storage did with a song of an import: added it as
+// ID, or found it already exists as ID and updated or skipped it.
+type ImportedSong struct {
+	ID     int
+	Action string
+}
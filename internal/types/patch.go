@@ -0,0 +1,76 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// Optional is a member of a JSON merge patch (RFC 7396). Set reports
+// whether the member was present in the document; for an explicit null
+// Value stays nil.
+type Optional[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+
+	if bytes.Equal(data, []byte("null")) {
+		o.Value = nil
+		return nil
+	}
+
+	o.Value = new(T)
+
+	return json.Unmarshal(data, o.Value)
+}
+
+// SongPatch holds the validated changes of a merge patch. Nil fields are
+// left as they are; AlbumID and TrackNumber are set to NULL when Set with
+// a nil Value.
+type SongPatch struct {
+	Song        *string
+	Group       *string
+	ReleaseDate *time.Time
+	Text        *string
+	Link        *string
+	AlbumID     Optional[int]
+	TrackNumber Optional[int]
+}
+
+// Empty reports whether p changes nothing.
+func (p *SongPatch) Empty() bool {
+	return p.Song == nil && p.Group == nil && p.ReleaseDate == nil && p.Text == nil && p.Link == nil &&
+		!p.AlbumID.Set && !p.TrackNumber.Set
+}
+
+// Apply returns a copy of song with p applied.
+func (p *SongPatch) Apply(song *Song) *Song {
+	patched := *song
+
+	if p.Song != nil {
+		patched.Song = *p.Song
+	}
+	if p.Group != nil {
+		patched.Group = *p.Group
+	}
+	if p.ReleaseDate != nil {
+		patched.ReleaseDate = p.ReleaseDate
+	}
+	if p.Text != nil {
+		patched.Text = *p.Text
+	}
+	if p.Link != nil {
+		patched.Link = *p.Link
+	}
+	if p.AlbumID.Set {
+		patched.AlbumID = p.AlbumID.Value
+	}
+	if p.TrackNumber.Set {
+		patched.TrackNumber = p.TrackNumber.Value
+	}
+
+	return &patched
+}
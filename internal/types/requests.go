@@ -1,8 +1,10 @@
 package types
 
 type SongRequest struct {
-	Song  string `json:"song"`
-	Group string `json:"group"`
+	Song        string `json:"song"`
+	Group       string `json:"group"`
+	AlbumID     *int   `json:"albumId,omitempty"`
+	TrackNumber *int   `json:"trackNumber,omitempty"`
 }
 
 type UpdateSongRequest struct {
@@ -11,4 +13,29 @@ type UpdateSongRequest struct {
 	ReleaseDate string `json:"releaseDate"`
 	Text        string `json:"text"`
 	Link        string `json:"link"`
+	AlbumID     *int   `json:"albumId,omitempty"`
+	TrackNumber *int   `json:"trackNumber,omitempty"`
+}
+
+type GroupRequest struct {
+	Name string `json:"name"`
+}
+
+type AlbumRequest struct {
+	Title       string `json:"title"`
+	Group       string `json:"group"`
+	ReleaseDate string `json:"releaseDate"`
+	CoverLink   string `json:"coverLink"`
+}
+
+// PatchSongRequest is a JSON merge patch for a song. Members that are
+// missing are left unchanged and null clears albumId or trackNumber.
+type PatchSongRequest struct {
+	Song        Optional[string] `json:"song" swaggertype:"string"`
+	Group       Optional[string] `json:"group" swaggertype:"string"`
+	ReleaseDate Optional[string] `json:"releaseDate" swaggertype:"string"`
+	Text        Optional[string] `json:"text" swaggertype:"string"`
+	Link        Optional[string] `json:"link" swaggertype:"string"`
+	AlbumID     Optional[int]    `json:"albumId" swaggertype:"integer"`
+	TrackNumber Optional[int]    `json:"trackNumber" swaggertype:"integer"`
 }
@@ -0,0 +1,75 @@
+package types
+
+import "time"
+
+// Actions recorded in song revisions.
+const (
+	RevisionAdd     = "add"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+)
+
+// Revision is a snapshot of a song taken after it was added, updated or
+// restored, or right before it was deleted.
+type Revision struct {
+	ID          int        `json:"id"`
+	SongID      int        `json:"songId"`
+	Version     int        `json:"version"`
+	Action      string     `json:"action"`
+	Author      string     `json:"author,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	Song        string     `json:"song"`
+	Group       string     `json:"group"`
+	ReleaseDate *time.Time `json:"releaseDate"`
+	Text        string     `json:"text"`
+	Link        string     `json:"link"`
+	AlbumID     *int       `json:"albumId,omitempty"`
+	TrackNumber *int       `json:"trackNumber,omitempty"`
+}
+
+type Revisions struct {
+	Revisions []*Revision `json:"revisions"`
+}
+
+// Verse diff operations.
+const (
+	VerseEqual  = "equal"
+	VerseInsert = "insert"
+	VerseDelete = "delete"
+)
+
+type VerseChange struct {
+	Op    string `json:"op"`
+	Verse string `json:"verse"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// RevisionDiff describes how the song changed from one revision to
+// another: the metadata fields that differ and the text verse by verse.
+type RevisionDiff struct {
+	From   int           `json:"from"`
+	To     int           `json:"to"`
+	Fields []FieldChange `json:"fields,omitempty"`
+	Verses []VerseChange `json:"verses"`
+}
+
+// SongAt returns the song as it was at r.
+func (r *Revision) SongAt() *Song {
+	return &Song{
+		ID:          r.SongID,
+		Song:        r.Song,
+		Group:       r.Group,
+		ReleaseDate: r.ReleaseDate,
+		Text:        r.Text,
+		Link:        r.Link,
+		AlbumID:     r.AlbumID,
+		TrackNumber: r.TrackNumber,
+		Version:     r.Version,
+	}
+}
@@ -1,14 +1,63 @@
 package types
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type Song struct {
+	ID               int        `json:"id"`
+	Song             string     `json:"song"`
+	GroupID          int        `json:"groupId"`
+	Group            string     `json:"group"`
+	ReleaseDate      *time.Time `json:"releaseDate"`
+	Text             string     `json:"text"`
+	Link             string     `json:"link"`
+	AlbumID          *int       `json:"albumId,omitempty"`
+	TrackNumber      *int       `json:"trackNumber,omitempty"`
+	Version          int        `json:"version"`
+	EnrichmentStatus string     `json:"enrichmentStatus"`
+	DeletedAt        *time.Time `json:"deletedAt,omitempty"`
+}
+
+type Trash struct {
+	Songs []*Song `json:"songs"`
+}
+
+// WriteOptions carries the preconditions of a song change. IfMatch is the
+// If-Match header of the request; the service resolves it to IfVersion,
+// the version storage only changes the song at. Zero means any version.
+// Author is recorded in the revision the change creates.
+type WriteOptions struct {
+	IfMatch   string
+	IfVersion int
+	Author    string
+}
+
+type Group struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type Groups struct {
+	Groups []*Group `json:"groups"`
+}
+
+type Album struct {
 	ID          int       `json:"id"`
-	Song        string    `json:"song"`
+	Title       string    `json:"title"`
+	GroupID     int       `json:"groupId"`
 	Group       string    `json:"group"`
 	ReleaseDate time.Time `json:"releaseDate"`
-	Text        string    `json:"text"`
-	Link        string    `json:"link"`
+	CoverLink   string    `json:"coverLink"`
+}
+
+type Albums struct {
+	Albums []*Album `json:"albums"`
+}
+
+type Tracks struct {
+	Tracks []*Song `json:"tracks"`
 }
 
 type Details struct {
@@ -18,16 +67,154 @@ type Details struct {
 }
 
 type Songs struct {
-	Songs []*Song `json:"songs"`
+	Songs      []*Song `json:"songs"`
+	Total      int     `json:"total"`
+	Page       int     `json:"page,omitempty"`
+	Size       int     `json:"size"`
+	TotalPages int     `json:"totalPages"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+	Links      Links   `json:"links"`
+}
+
+type Links struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+type SongsPage struct {
+	Songs []*Song
+	Next  *Cursor
+	Total int
+}
+
+type SearchResult struct {
+	ID          int        `json:"id"`
+	Song        string     `json:"song"`
+	Group       string     `json:"group"`
+	ReleaseDate *time.Time `json:"releaseDate"`
+	Link        string     `json:"link"`
+	Snippets    []string   `json:"snippets"`
+}
+
+type SearchResults struct {
+	Results []*SearchResult `json:"results"`
 }
 
 type Pagination struct {
-	Page int
-	Size int
+	Page   int
+	Size   int
+	Cursor *Cursor
+}
+
+// Offset returns the number of rows to skip. Cursor pagination never skips
+// rows: the cursor itself marks where the page starts.
+func (p Pagination) Offset() int {
+	if p.Cursor != nil {
+		return 0
+	}
+	return (p.Page - 1) * p.Size
 }
 
+// Cursor marks the last song of a page. It carries the value of every sort
+// key of that song, so the next page can start right after it no matter
+// how many songs were added or removed meanwhile.
+type Cursor struct {
+	Sort        string     `json:"s"`
+	ID          int        `json:"id"`
+	Song        string     `json:"song,omitempty"`
+	Group       string     `json:"group,omitempty"`
+	ReleaseDate *time.Time `json:"date,omitempty"`
+	Rank        float64    `json:"rank,omitempty"`
+}
+
+const (
+	MatchExact  = "exact"
+	MatchPrefix = "prefix"
+	MatchFuzzy  = "fuzzy"
+)
+
 type Filter struct {
-	Song  string
-	Group string
-	Date  *time.Time
+	Song     string
+	Group    string
+	GroupID  int
+	Date     *time.Time
+	DateFrom *time.Time
+	DateTo   *time.Time
+	Match    string
+}
+
+const (
+	SortByID          = "id"
+	SortBySong        = "song"
+	SortByGroup       = "group"
+	SortByReleaseDate = "releaseDate"
+
+	// SortByRank orders fuzzy matches by relevance. It is not accepted
+	// from clients and only applies when no explicit sort is given.
+	SortByRank = "rank"
+)
+
+// SortFields is the allowlist of fields songs can be sorted by.
+var SortFields = map[string]bool{
+	SortByID:          true,
+	SortBySong:        true,
+	SortByGroup:       true,
+	SortByReleaseDate: true,
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+type SongsQuery struct {
+	Filter     Filter
+	Sort       []SortField
+	Pagination Pagination
+}
+
+func (q SongsQuery) Ranked() bool {
+	return q.Filter.Match == MatchFuzzy && (q.Filter.Song != "" || q.Filter.Group != "")
+}
+
+// SortKeys returns the complete ordering of q: relevance for fuzzy matches
+// without an explicit sort, then the requested fields, then id as a
+// tiebreaker so that the order is total.
+func (q SongsQuery) SortKeys() []SortField {
+	var keys []SortField
+
+	if len(q.Sort) == 0 && q.Ranked() {
+		keys = append(keys, SortField{Field: SortByRank, Desc: true})
+	}
+
+	keys = append(keys, q.Sort...)
+
+	for _, k := range keys {
+		if k.Field == SortByID {
+			return keys
+		}
+	}
+
+	return append(keys, SortField{Field: SortByID})
+}
+
+// SortSignature identifies the ordering of q. Cursors remember it, so a
+// cursor issued for one ordering can't be replayed against another.
+func (q SongsQuery) SortSignature() string {
+	keys := q.SortKeys()
+	parts := make([]string, len(keys))
+
+	for i, k := range keys {
+		parts[i] = k.Field
+		if k.Desc {
+			parts[i] = "-" + k.Field
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+type SearchQuery struct {
+	Query      string
+	Pagination Pagination
 }
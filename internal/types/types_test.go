@@ -0,0 +1,38 @@
+package types
+
+import "testing"
+
+func TestSongsQuerySortSignature(t *testing.T) {
+	tests := []struct {
+		name string
+		q    SongsQuery
+		want string
+	}{
+		{"default", SongsQuery{}, "id"},
+		{"id desc", SongsQuery{Sort: []SortField{{Field: SortByID, Desc: true}}}, "-id"},
+		{"id tiebreaker", SongsQuery{Sort: []SortField{{Field: SortBySong}}}, "song,id"},
+		{"several fields", SongsQuery{Sort: []SortField{
+			{Field: SortByGroup},
+			{Field: SortByReleaseDate, Desc: true},
+		}}, "group,-releaseDate,id"},
+		{"id in the middle", SongsQuery{Sort: []SortField{
+			{Field: SortByID, Desc: true},
+			{Field: SortBySong},
+		}}, "-id,song"},
+		{"fuzzy ranked", SongsQuery{Filter: Filter{Song: "creep", Match: MatchFuzzy}}, "-rank,id"},
+		{"fuzzy with sort", SongsQuery{
+			Filter: Filter{Song: "creep", Match: MatchFuzzy},
+			Sort:   []SortField{{Field: SortBySong}},
+		}, "song,id"},
+		{"fuzzy without terms", SongsQuery{Filter: Filter{Match: MatchFuzzy}}, "id"},
+		{"prefix", SongsQuery{Filter: Filter{Song: "cr", Match: MatchPrefix}}, "id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.q.SortSignature(); got != tt.want {
+				t.Errorf("SortSignature() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
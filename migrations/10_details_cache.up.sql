@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS details_cache (
+	key TEXT PRIMARY KEY,
+	found BOOLEAN NOT NULL,
+	release_date VARCHAR(10) NOT NULL DEFAULT '',
+	text TEXT NOT NULL DEFAULT '',
+	link TEXT NOT NULL DEFAULT '',
+	expires_at TIMESTAMPTZ NOT NULL,
+	accessed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_details_cache_accessed_at ON details_cache(accessed_at);
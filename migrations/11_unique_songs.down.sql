@@ -0,0 +1,2 @@
+-- Duplicates moved to the trash by the up migration stay there.
+DROP INDEX IF EXISTS idx_songs_unique_song;
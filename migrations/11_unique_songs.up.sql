@@ -0,0 +1,29 @@
+-- Songs are unique by name within their group, ignoring case and extra
+-- whitespace. Of the existing duplicates the oldest song is kept and the
+-- others are moved to the trash, with a revision recording the move.
+WITH duplicates AS (
+	SELECT id
+	FROM (
+		SELECT id, ROW_NUMBER() OVER (
+			PARTITION BY LOWER(regexp_replace(btrim(song), '\s+', ' ', 'g')), group_id
+			ORDER BY id
+		) AS n
+		FROM songs
+		WHERE deleted_at IS NULL
+	) ranked
+	WHERE n > 1
+), trashed AS (
+	UPDATE songs s
+	SET deleted_at = now(), version = s.version + 1
+	FROM duplicates d
+	WHERE s.id = d.id
+	RETURNING s.id, s.version, s.song, s.group_id, s.release_date, s.text, s.link, s.album_id, s.track_number
+)
+INSERT INTO song_revisions(song_id, version, action, song, group_name, release_date, text, link, album_id, track_number, author)
+SELECT t.id, t.version, 'delete', t.song, g.name, t.release_date, t.text, t.link, t.album_id, t.track_number, 'migration'
+FROM trashed t
+JOIN groups g ON g.id = t.group_id
+ORDER BY t.id;
+
+CREATE UNIQUE INDEX idx_songs_unique_song ON songs(LOWER(regexp_replace(btrim(song), '\s+', ' ', 'g')), group_id)
+WHERE deleted_at IS NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_group_trgm;
+
+DROP INDEX IF EXISTS idx_song_trgm;
@@ -0,0 +1,5 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_song_trgm ON songs USING GIN (song gin_trgm_ops);
+
+CREATE INDEX idx_group_trgm ON songs USING GIN (group_name gin_trgm_ops);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_text_search;
+
+ALTER TABLE songs DROP COLUMN IF EXISTS text_search;
@@ -0,0 +1,5 @@
+ALTER TABLE songs
+	ADD COLUMN text_search tsvector
+	GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(text, ''))) STORED;
+
+CREATE INDEX idx_text_search ON songs USING GIN (text_search);
@@ -0,0 +1,18 @@
+ALTER TABLE songs ADD COLUMN group_name VARCHAR(255);
+
+UPDATE songs s
+SET group_name = g.name
+FROM groups g
+WHERE g.id = s.group_id;
+
+ALTER TABLE songs ALTER COLUMN group_name SET NOT NULL;
+
+ALTER TABLE songs DROP COLUMN group_id;
+
+DROP TABLE IF EXISTS groups;
+
+CREATE INDEX idx_song_group_date ON songs(song, group_name, release_date);
+
+CREATE INDEX idx_group_date ON songs(group_name, release_date);
+
+CREATE INDEX idx_group_trgm ON songs USING GIN (group_name gin_trgm_ops);
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS groups (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_groups_name ON groups(LOWER(name));
+
+CREATE INDEX idx_groups_name_trgm ON groups USING GIN (name gin_trgm_ops);
+
+-- Group names are normalized by trimming and collapsing inner whitespace;
+-- spellings that differ only in case or spacing become one group, named
+-- after its earliest song.
+INSERT INTO groups (name)
+SELECT DISTINCT ON (LOWER(regexp_replace(btrim(group_name), '\s+', ' ', 'g')))
+	regexp_replace(btrim(group_name), '\s+', ' ', 'g')
+FROM songs
+ORDER BY LOWER(regexp_replace(btrim(group_name), '\s+', ' ', 'g')), id;
+
+ALTER TABLE songs ADD COLUMN group_id INT REFERENCES groups(id);
+
+UPDATE songs s
+SET group_id = g.id
+FROM groups g
+WHERE LOWER(g.name) = LOWER(regexp_replace(btrim(s.group_name), '\s+', ' ', 'g'));
+
+ALTER TABLE songs ALTER COLUMN group_id SET NOT NULL;
+
+-- Dropping the column also drops idx_song_group_date, idx_group_date and
+-- idx_group_trgm.
+ALTER TABLE songs DROP COLUMN group_name;
+
+CREATE INDEX idx_song_group_date ON songs(song, group_id, release_date);
+
+CREATE INDEX idx_group_date ON songs(group_id, release_date);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_album_track;
+
+ALTER TABLE songs
+	DROP COLUMN IF EXISTS track_number,
+	DROP COLUMN IF EXISTS album_id;
+
+DROP TABLE IF EXISTS albums;
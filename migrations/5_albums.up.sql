@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS albums (
+	id SERIAL PRIMARY KEY,
+	title VARCHAR(255) NOT NULL,
+	group_id INT NOT NULL REFERENCES groups(id),
+	release_date DATE NOT NULL,
+	cover_link VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_albums_group ON albums(group_id);
+
+ALTER TABLE songs
+	ADD COLUMN album_id INT REFERENCES albums(id),
+	ADD COLUMN track_number INT CHECK (track_number > 0);
+
+CREATE UNIQUE INDEX idx_album_track ON songs(album_id, track_number);
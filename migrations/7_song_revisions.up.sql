@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS song_revisions (
+	id SERIAL PRIMARY KEY,
+	song_id INT NOT NULL,
+	version INT NOT NULL,
+	action VARCHAR(16) NOT NULL CHECK (action IN ('add', 'update', 'delete', 'restore')),
+	song VARCHAR(255) NOT NULL,
+	group_name VARCHAR(255) NOT NULL,
+	release_date DATE NOT NULL,
+	text TEXT,
+	link VARCHAR(255),
+	album_id INT,
+	track_number INT,
+	author VARCHAR(255) NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_song_revisions_song ON song_revisions(song_id, id);
+
+INSERT INTO song_revisions(song_id, version, action, song, group_name, release_date, text, link, album_id, track_number)
+SELECT s.id, s.version, 'add', s.song, g.name, s.release_date, s.text, s.link, s.album_id, s.track_number
+FROM songs s
+JOIN groups g ON g.id = s.group_id
+ORDER BY s.id;
@@ -0,0 +1,19 @@
+-- Without deleted_at, songs in the trash would come back as live songs and
+-- could clash on their track numbers, so the trash has to be restored or
+-- purged first.
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM songs WHERE deleted_at IS NOT NULL) THEN
+		RAISE EXCEPTION 'songs in the trash exist, restore or purge them before migrating down';
+	END IF;
+END
+$$;
+
+DROP INDEX IF EXISTS idx_album_track;
+
+CREATE UNIQUE INDEX idx_album_track ON songs(album_id, track_number);
+
+DROP INDEX IF EXISTS idx_songs_deleted_at;
+
+ALTER TABLE songs
+	DROP COLUMN IF EXISTS deleted_at;
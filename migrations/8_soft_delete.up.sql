@@ -0,0 +1,9 @@
+ALTER TABLE songs
+	ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX idx_songs_deleted_at ON songs(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Songs in the trash don't hold on to their track numbers.
+DROP INDEX IF EXISTS idx_album_track;
+
+CREATE UNIQUE INDEX idx_album_track ON songs(album_id, track_number) WHERE deleted_at IS NULL;
@@ -0,0 +1,25 @@
+-- Songs that were never enriched have no release date. Restoring NOT NULL
+-- would lose them, so they have to be dated or deleted by hand first.
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM songs WHERE release_date IS NULL)
+		OR EXISTS (SELECT 1 FROM song_revisions WHERE release_date IS NULL) THEN
+		RAISE EXCEPTION 'songs or song revisions without a release date exist, set their release_date before migrating down';
+	END IF;
+END
+$$;
+
+DROP INDEX IF EXISTS idx_songs_enrichment_status;
+
+DROP INDEX IF EXISTS idx_songs_enrichment_due;
+
+ALTER TABLE song_revisions
+	ALTER COLUMN release_date SET NOT NULL;
+
+ALTER TABLE songs
+	DROP COLUMN IF EXISTS enrichment_updated_at,
+	DROP COLUMN IF EXISTS enrichment_next_at,
+	DROP COLUMN IF EXISTS enrichment_error,
+	DROP COLUMN IF EXISTS enrichment_attempts,
+	DROP COLUMN IF EXISTS enrichment_status,
+	ALTER COLUMN release_date SET NOT NULL;
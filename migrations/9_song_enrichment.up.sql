@@ -0,0 +1,17 @@
+-- Songs are stored before their details are fetched, so the release date
+-- stays empty until enrichment succeeds.
+ALTER TABLE songs
+	ALTER COLUMN release_date DROP NOT NULL,
+	ADD COLUMN enrichment_status VARCHAR(16) NOT NULL DEFAULT 'done'
+		CHECK (enrichment_status IN ('pending', 'done', 'failed')),
+	ADD COLUMN enrichment_attempts INT NOT NULL DEFAULT 0,
+	ADD COLUMN enrichment_error TEXT,
+	ADD COLUMN enrichment_next_at TIMESTAMPTZ,
+	ADD COLUMN enrichment_updated_at TIMESTAMPTZ;
+
+ALTER TABLE song_revisions
+	ALTER COLUMN release_date DROP NOT NULL;
+
+CREATE INDEX idx_songs_enrichment_due ON songs(enrichment_next_at) WHERE enrichment_status = 'pending';
+
+CREATE INDEX idx_songs_enrichment_status ON songs(enrichment_status, id) WHERE enrichment_status <> 'done';